@@ -11,51 +11,71 @@ import (
 
 func main() {
 	var (
-		pattern  string
-		location string
-		opts     search.Options
+		pattern string
+		opts    search.Options
 	)
 
 	parser := flags.NewParser(&opts, flags.Default)
-	parser.Usage = "[OPTIONS] PATTERN [PATH]"
+	parser.Usage = "[OPTIONS] PATTERN [PATH...]"
 	args, err := parser.Parse()
 
+	// go-flags has already printed the error or help message
 	if err != nil {
-		logger.Fail(err.Error())
+		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+		os.Exit(1)
 	}
 
-	if len(args) == 0 {
-		parser.WriteHelp(os.Stdout)
-		os.Exit(0)
-	} else {
-		pattern = args[0]
+	if opts.TypeList {
+		search.New(&opts).ListTypes()
+		return
 	}
 
-	if len(args) > 1 {
-		location = args[1]
+	if opts.Undo {
+		if len(args) > 0 || len(opts.Patterns) > 0 {
+			logger.Fail("--undo doesn't take a PATTERN or PATH")
+		}
+		if err := search.New(&opts).Undo(); err != nil {
+			logger.Fail(err.Error())
+		}
+		return
 	}
 
-	if pattern == "" {
+	// Patterns given with -e leave all arguments for the paths, as do
+	// --files and --find-file, which don't take a pattern
+	needsPattern := opts.FindFile == nil && !opts.Files
+	if needsPattern && len(opts.Patterns) == 0 {
+		if len(args) == 0 {
+			parser.WriteHelp(os.Stdout)
+			os.Exit(0)
+		}
+		pattern, args = args[0], args[1:]
+	}
+
+	if needsPattern && pattern == "" && len(opts.Patterns) == 0 {
 		parser.WriteHelp(os.Stdout)
 		os.Exit(0)
 	}
 
-	if location == "" {
-		location, err = os.Getwd()
-		if err != nil {
-			logger.Fail(err.Error())
+	// Without any paths, search piped input (unless stdin is needed for
+	// --interactive, files are being rewritten or only file names are being
+	// matched, found or listed), or the current directory
+	if len(args) == 0 && opts.FilesFrom == "" {
+		usesFiles := opts.Write || opts.Diff || opts.Interactive || opts.Names || opts.Filename || opts.FindFile != nil || opts.Files
+		if !usesFiles && search.PipedStdin() {
+			args = []string{"-"}
+		} else {
+			wd, err := os.Getwd()
+			if err != nil {
+				logger.Fail(err.Error())
+			}
+			args = []string{wd}
 		}
 	}
 
-	search.New(&search.Options{
-		Pattern:  pattern,
-		Location: location,
-
-		IgnoreCase:   opts.IgnoreCase,
-		Quiet:        opts.Quiet,
-		Hidden:       opts.Hidden,
-		Unrestricted: opts.Unrestricted,
-		Debug:        opts.Debug,
-		ShowStats:    opts.ShowStats,
-	}).Run()
+	opts.Pattern = pattern
+	opts.Locations = args
+
+	search.New(&opts).Run()
 }
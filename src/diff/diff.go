@@ -0,0 +1,317 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const noNewline = "\\ No newline at end of file\n"
+
+// Op is a single line of an edit script
+type Op struct {
+	// ' ' for an unchanged line, '-' for a deletion and '+' for an insertion
+	Kind byte
+	// The line, including its newline if it has one
+	Line string
+}
+
+// Lines splits text into lines, keeping their newlines
+func Lines(text []byte) []string {
+	var lines []string
+	for len(text) > 0 {
+		i := bytes.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines = append(lines, string(text[:i]))
+		text = text[i:]
+	}
+	return lines
+}
+
+// Change replaces the bytes Start:End of a text with Text
+type Change struct {
+	Start, End int
+	Text       []byte
+}
+
+// Edits returns the edit script for making changes to a, which must be in
+// order and not overlap. Since the changes are already known, this takes time
+// linear in the size of a rather than diffing it with the changed text. Each
+// line a change touches is replaced, and changes on the same line are
+// grouped together.
+func Edits(a []byte, changes []Change) []Op {
+	lines := Lines(a)
+
+	// The offset each line starts at, and the line the offset o is on
+	starts := make([]int, len(lines)+1)
+	for i, l := range lines {
+		starts[i+1] = starts[i] + len(l)
+	}
+	lineOf := func(o int) int {
+		// The end of a last line without a newline is still on it
+		if o == len(a) && o > 0 && a[o-1] != '\n' {
+			return len(lines) - 1
+		}
+		return sort.Search(len(lines), func(i int) bool { return starts[i+1] > o })
+	}
+	lineEnd := func(o int) int {
+		if l := lineOf(o); l < len(lines) {
+			return starts[l+1]
+		}
+		return len(a)
+	}
+
+	var ops []Op
+	next := 0 // the next line of a to add
+	for i := 0; i < len(changes); {
+		first := lineOf(changes[i].Start)
+		for ; next < first; next++ {
+			ops = append(ops, Op{' ', lines[next]})
+		}
+
+		// Collect the changes on the group's lines, along with the text
+		// between them, extending it to the end of the line after the last
+		// change. If that leaves the new text without a newline at the end
+		// (because a change removed it), the next line is joined to it.
+		var text []byte
+		pos, end := starts[first], starts[first]
+		for started := false; ; started = true {
+			if i < len(changes) && (!started || changes[i].Start < end) {
+				c := changes[i]
+				text = append(text, a[pos:c.Start]...)
+				text = append(text, c.Text...)
+				pos = c.End
+				if c.End > c.Start && a[c.End-1] == '\n' {
+					end = c.End
+				} else {
+					end = lineEnd(c.End)
+				}
+				i++
+				continue
+			}
+			if end < len(a) && len(text)+end-pos > 0 && !endsWithNewline(text, a[pos:end]) {
+				end = lineEnd(end)
+				continue
+			}
+			break
+		}
+		text = append(text, a[pos:end]...)
+
+		for ; next < len(lines) && starts[next] < end; next++ {
+			ops = append(ops, Op{'-', lines[next]})
+		}
+		for _, l := range Lines(text) {
+			ops = append(ops, Op{'+', l})
+		}
+	}
+	for ; next < len(lines); next++ {
+		ops = append(ops, Op{' ', lines[next]})
+	}
+	return ops
+}
+
+// Reports whether the concatenation of a and b ends with a newline
+func endsWithNewline(a, b []byte) bool {
+	if len(b) > 0 {
+		return b[len(b)-1] == '\n'
+	}
+	return len(a) > 0 && a[len(a)-1] == '\n'
+}
+
+// Unified returns a unified diff of making changes to a, with the given number
+// of lines of context around each change, in the format understood by patch
+// and git apply. It returns an empty string if there are no changes.
+func Unified(fromName, toName string, a []byte, changes []Change, context int) string {
+	var out strings.Builder
+	for _, h := range Hunks(Edits(a, changes), context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(h.FromLine, h.FromCount), hunkRange(h.ToLine, h.ToCount))
+		for _, op := range h.Ops {
+			out.WriteByte(op.Kind)
+			out.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				out.WriteByte('\n')
+				out.WriteString(noNewline)
+			}
+		}
+	}
+	return out.String()
+}
+
+// Hunk is a group of nearby changes along with their surrounding context
+type Hunk struct {
+	// 1-based starting lines and line counts in the old and new text
+	FromLine, FromCount int
+	ToLine, ToCount     int
+
+	Ops []Op
+}
+
+// Hunks groups the changes of an edit script into hunks, merging changes
+// which are within 2*context lines of each other
+func Hunks(ops []Op, context int) []Hunk {
+	var (
+		hunks            []Hunk
+		fromLine, toLine = 1, 1
+
+		// The range of ops in the current hunk, and its first line numbers
+		start, end         = -1, -1
+		startFrom, startTo int
+	)
+
+	flush := func() {
+		if start < 0 {
+			return
+		}
+		stop := end + context
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		h := Hunk{FromLine: startFrom, ToLine: startTo, Ops: ops[start:stop]}
+		for _, op := range h.Ops {
+			if op.Kind != '+' {
+				h.FromCount++
+			}
+			if op.Kind != '-' {
+				h.ToCount++
+			}
+		}
+		hunks = append(hunks, h)
+		start = -1
+	}
+
+	for i, op := range ops {
+		if op.Kind != ' ' {
+			if start >= 0 && i > end+2*context {
+				flush()
+			}
+			if start < 0 {
+				// Back up over the leading context
+				start = i - context
+				if start < 0 {
+					start = 0
+				}
+				// Context lines are in both texts
+				startFrom, startTo = fromLine-(i-start), toLine-(i-start)
+			}
+			end = i + 1
+		}
+
+		if op.Kind != '+' {
+			fromLine++
+		}
+		if op.Kind != '-' {
+			toLine++
+		}
+	}
+	flush()
+
+	return hunks
+}
+
+// Formats a hunk range, which by convention refers to the line before the
+// hunk when it's empty
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// Apply applies a unified diff, as returned by Unified, to text. It fails if
+// any of the lines the diff removes or keeps as context don't match.
+func Apply(text []byte, patch string) ([]byte, error) {
+	lines := Lines(text)
+	patchLines := Lines([]byte(patch))
+
+	var out bytes.Buffer
+	next := 0 // index of the next line of text to copy
+	for i := 0; i < len(patchLines); i++ {
+		l := patchLines[i]
+		if strings.HasPrefix(l, "--- ") || strings.HasPrefix(l, "+++ ") {
+			continue
+		}
+
+		if !strings.HasPrefix(l, "@@ ") {
+			return nil, fmt.Errorf("line %d of the patch isn't part of a hunk", i+1)
+		}
+		fromLine, fromCount, err := parseHunkHeader(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the patch: %v", i+1, err)
+		}
+
+		// Hunks with no old lines refer to the line before them
+		start := fromLine - 1
+		if fromCount == 0 {
+			start = fromLine
+		}
+		if start < next || start > len(lines) {
+			return nil, fmt.Errorf("hunk at line %d of the patch is out of range", i+1)
+		}
+		for _, l := range lines[next:start] {
+			out.WriteString(l)
+		}
+		next = start
+
+		for i+1 < len(patchLines) && !strings.HasPrefix(patchLines[i+1], "@@ ") {
+			i++
+			op := patchLines[i]
+			if op == "" {
+				continue
+			}
+			line := op[1:]
+			if i+1 < len(patchLines) && patchLines[i+1] == noNewline {
+				line = strings.TrimSuffix(line, "\n")
+				i++
+			}
+
+			switch op[0] {
+			case ' ', '-':
+				if next >= len(lines) || lines[next] != line {
+					return nil, fmt.Errorf("line %d doesn't match line %d of the patch", next+1, i+1)
+				}
+				if op[0] == ' ' {
+					out.WriteString(line)
+				}
+				next++
+			case '+':
+				out.WriteString(line)
+			default:
+				return nil, fmt.Errorf("invalid line %d of the patch", i+1)
+			}
+		}
+	}
+
+	for _, l := range lines[next:] {
+		out.WriteString(l)
+	}
+	return out.Bytes(), nil
+}
+
+// Parses the old range of a "@@ -l,s +l,s @@" hunk header
+func parseHunkHeader(header string) (line, count int, err error) {
+	var from string
+	if _, err := fmt.Sscanf(header, "@@ -%s", &from); err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(header))
+	}
+	count = 1
+	if i := strings.IndexByte(from, ','); i >= 0 {
+		if count, err = strconv.Atoi(from[i+1:]); err != nil {
+			return 0, 0, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(header))
+		}
+		from = from[:i]
+	}
+	if line, err = strconv.Atoi(from); err != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", strings.TrimSpace(header))
+	}
+	return line, count, nil
+}
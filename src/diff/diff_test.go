@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Replaces the first occurrence of old after offset from in a
+func replace(a []byte, from int, old, text string) Change {
+	start := from + bytes.Index(a[from:], []byte(old))
+	return Change{start, start + len(old), []byte(text)}
+}
+
+// Returns a with changes made
+func content(a []byte, changes []Change) []byte {
+	var buf []byte
+	last := 0
+	for _, c := range changes {
+		buf = append(buf, a[last:c.Start]...)
+		buf = append(buf, c.Text...)
+		last = c.End
+	}
+	return append(buf, a[last:]...)
+}
+
+func TestEdits(t *testing.T) {
+	a := []byte("a\nb b\nc\nd\n")
+	ops := Edits(a, []Change{replace(a, 0, "b", "x"), replace(a, 3, "b", "y"), {len(a), len(a), []byte("e\n")}})
+	assert.Equal(t, []Op{{' ', "a\n"}, {'-', "b b\n"}, {'+', "x y\n"}, {' ', "c\n"}, {' ', "d\n"}, {'+', "e\n"}}, ops)
+
+	// Removing a newline joins the line after it
+	assert.Equal(t, []Op{{'-', "a\n"}, {'-', "b b\n"}, {'+', "a b b\n"}, {' ', "c\n"}, {' ', "d\n"}},
+		Edits(a, []Change{{1, 2, []byte(" ")}}))
+
+	assert.Equal(t, []Op{{'-', "x"}, {'+', "xy"}}, Edits([]byte("x"), []Change{{1, 1, []byte("y")}}))
+	assert.Equal(t, []Op{{'+', "a"}}, Edits(nil, []Change{{0, 0, []byte("a")}}))
+	assert.Nil(t, Edits(nil, nil))
+}
+
+func TestUnified(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+	changes := []Change{replace(a, 0, "2", "two"), replace(a, 20, "12\n", "twelve")}
+	assert.Equal(t, `--- a/n.txt
++++ b/n.txt
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+\ No newline at end of file
+`, Unified("a/n.txt", "b/n.txt", a, changes, 3))
+
+	assert.Equal(t, "", Unified("a", "b", a, nil, 3))
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n", Unified("a", "b", nil, []Change{{0, 0, []byte("x\n")}}, 3))
+}
+
+func TestApply(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+
+	for _, changes := range [][]Change{
+		{{0, 0, []byte("0\n")}, replace(a, 0, "2", "two"), replace(a, 10, "7\n", ""), replace(a, 20, "12\n", "twelve")},
+		{{0, len(a), nil}},
+		{replace(a, 0, "3\n4", "34")},
+		nil,
+	} {
+		b := content(a, changes)
+		patched, err := Apply(a, Unified("a", "b", a, changes, 3))
+		assert.NoError(t, err)
+		assert.Equal(t, string(b), string(patched))
+
+		// Fewer lines of context change where hunks split
+		patched, err = Apply(a, Unified("a", "b", a, changes, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, string(b), string(patched))
+	}
+
+	_, err := Apply([]byte("1\nchanged\n3\n"), Unified("a", "b", a, []Change{replace(a, 0, "2", "two")}, 3))
+	assert.Error(t, err)
+}
+
+// Every line of a large file changing, as with a rename across a generated
+// file, takes time and memory in proportion to its size
+func TestUnifiedLarge(t *testing.T) {
+	var text strings.Builder
+	for i := 0; i < 200000; i++ {
+		text.WriteString("userId " + strconv.Itoa(i) + "\n")
+	}
+	a := []byte(text.String())
+
+	var changes []Change
+	for from := 0; bytes.Contains(a[from:], []byte("userId")); {
+		c := replace(a, from, "userId", "accountId")
+		changes = append(changes, c)
+		from = c.End
+	}
+
+	patch := Unified("a", "b", a, changes, 3)
+	assert.True(t, strings.HasPrefix(patch, "--- a\n+++ b\n@@ -1,200000 +1,200000 @@\n-userId 0\n"), patch[:100])
+	patched, err := Apply(a, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, string(content(a, changes)), string(patched))
+}
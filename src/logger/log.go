@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
 
@@ -11,6 +12,7 @@ var (
 	DebugMode bool
 
 	highlightError = color.New(color.FgRed).Add(color.Bold)
+	highlightWarn  = color.New(color.FgYellow).Add(color.Bold)
 	highlightGreen = color.New(color.FgGreen).Add(color.Bold)
 )
 
@@ -26,7 +28,11 @@ func DebugGreen(a string, s ...interface{}) {
 	}
 }
 
+func Warn(a string, s ...interface{}) {
+	fmt.Fprintln(os.Stderr, highlightWarn.Sprintf(a, s...))
+}
+
 func Fail(a string, s ...interface{}) {
-	highlightError.Sprintf(a, s...)
+	fmt.Fprintln(os.Stderr, highlightError.Sprintf(a, s...))
 	os.Exit(1)
 }
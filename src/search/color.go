@@ -0,0 +1,366 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/wellsjo/SuperSearch/src/syntax"
+)
+
+// Output elements that can be colored with --colors
+const (
+	rolePath      = "path"
+	roleLine      = "line"
+	roleColumn    = "column"
+	roleMatch     = "match"
+	roleContext   = "context"
+	roleSeparator = "separator"
+
+	// Used with --syntax
+	roleKeyword = "keyword"
+	roleString  = "string"
+	roleComment = "comment"
+	roleNumber  = "number"
+)
+
+var (
+	colorNames = map[string]color.Attribute{
+		"black":   color.FgBlack,
+		"red":     color.FgRed,
+		"green":   color.FgGreen,
+		"yellow":  color.FgYellow,
+		"blue":    color.FgBlue,
+		"magenta": color.FgMagenta,
+		"cyan":    color.FgCyan,
+		"white":   color.FgWhite,
+	}
+
+	styleNames = map[string]color.Attribute{
+		"bold":      color.Bold,
+		"faint":     color.Faint,
+		"italic":    color.Italic,
+		"underline": color.Underline,
+		"blink":     color.BlinkSlow,
+		"reverse":   color.ReverseVideo,
+	}
+
+	// GREP_COLORS capabilities and the elements they apply to. "mc" (the
+	// color of matches in context lines) is ignored like other unsupported
+	// capabilities, since context lines never contain matches without an
+	// inverted search.
+	grepColorRoles = map[string]string{
+		"mt": roleMatch,
+		"ms": roleMatch,
+		"fn": rolePath,
+		"ln": roleLine,
+		"bn": roleColumn,
+		"se": roleSeparator,
+		"cx": roleContext,
+	}
+
+	// The element colored for each syntax.Kind; plain text isn't colored
+	syntaxRoles = []string{
+		syntax.Plain:   "",
+		syntax.Keyword: roleKeyword,
+		syntax.String:  roleString,
+		syntax.Comment: roleComment,
+		syntax.Number:  roleNumber,
+	}
+
+	// Matches of additional patterns cycle through these background colors
+	patternBackgrounds = []color.Attribute{
+		color.BgCyan,
+		color.BgMagenta,
+		color.BgGreen,
+		color.BgRed,
+		color.BgBlue,
+	}
+)
+
+// style is the set of SGR attributes used to color one output element
+type style struct {
+	fg     []color.Attribute
+	bg     []color.Attribute
+	styles []color.Attribute
+}
+
+// Returns s with the colors and styles set by over layered on top of it
+func (s *style) with(over *style) *style {
+	layered := *s
+	if len(over.fg) > 0 {
+		layered.fg = over.fg
+	}
+	if len(over.bg) > 0 {
+		layered.bg = over.bg
+	}
+	layered.styles = append(append([]color.Attribute{}, s.styles...), over.styles...)
+	return &layered
+}
+
+func (s *style) color() *color.Color {
+	attrs := append(append(append([]color.Attribute{}, s.fg...), s.bg...), s.styles...)
+	if len(attrs) == 0 {
+		return nil
+	}
+	return color.New(attrs...)
+}
+
+// theme holds the colors for each element of the output. A nil color means
+// the element is printed as-is.
+type theme struct {
+	path      *color.Color
+	line      *color.Color
+	column    *color.Color
+	separator *color.Color
+	context   *color.Color
+
+	// Indexed by pattern
+	matches []*color.Color
+
+	// Indexed by syntax.Kind, for matching lines and for context lines,
+	// which have the context style layered on top
+	syntax        []*color.Color
+	contextSyntax []*color.Color
+
+	// Path colors from LS_COLORS, by file name suffix such as ".go"
+	fileColors map[string]*color.Color
+}
+
+// newTheme builds the output colors from the defaults, then the LS_COLORS and
+// GREP_COLORS environment variables, then each of the --colors specs in order.
+func newTheme(specs []string, numPatterns int) (*theme, error) {
+	styles := map[string]*style{
+		rolePath:      {fg: []color.Attribute{color.FgCyan}, styles: []color.Attribute{color.Bold}},
+		roleLine:      {fg: []color.Attribute{color.FgGreen}, styles: []color.Attribute{color.Bold}},
+		roleColumn:    {fg: []color.Attribute{color.FgGreen}, styles: []color.Attribute{color.Bold}},
+		roleSeparator: {fg: []color.Attribute{color.FgGreen}, styles: []color.Attribute{color.Bold}},
+		roleContext:   {},
+		roleKeyword:   {fg: []color.Attribute{color.FgMagenta}},
+		roleString:    {fg: []color.Attribute{color.FgGreen}},
+		roleComment:   {fg: []color.Attribute{color.FgHiBlack}},
+		roleNumber:    {fg: []color.Attribute{color.FgCyan}},
+		roleMatch: {
+			fg:     []color.Attribute{color.FgBlack},
+			bg:     []color.Attribute{color.BgYellow},
+			styles: []color.Attribute{color.Bold},
+		},
+	}
+	for i := 1; i < numPatterns; i++ {
+		styles[patternRole(i)] = &style{
+			fg:     []color.Attribute{color.FgBlack},
+			bg:     []color.Attribute{patternBackgrounds[(i-1)%len(patternBackgrounds)]},
+			styles: []color.Attribute{color.Bold},
+		}
+	}
+
+	fileColors := applyLSColors(styles, os.Getenv("LS_COLORS"))
+
+	grepColors := os.Getenv("GREP_COLORS")
+	if err := applyGrepColors(styles, grepColors); err != nil {
+		return nil, err
+	}
+
+	for _, spec := range specs {
+		if err := applyColorSpec(styles, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &theme{
+		path:      styles[rolePath].color(),
+		line:      styles[roleLine].color(),
+		column:    styles[roleColumn].color(),
+		separator: styles[roleSeparator].color(),
+		context:   styles[roleContext].color(),
+	}
+	for _, role := range syntaxRoles {
+		s := &style{}
+		if role != "" {
+			s = styles[role]
+		}
+		t.syntax = append(t.syntax, s.color())
+		t.contextSyntax = append(t.contextSyntax, s.with(styles[roleContext]).color())
+	}
+	for i := 0; i < numPatterns; i++ {
+		t.matches = append(t.matches, styles[patternRole(i)].color())
+	}
+
+	// A path color given with GREP_COLORS or --colors applies to every path
+	custom := strings.Contains(":"+grepColors, ":fn=")
+	for _, spec := range specs {
+		custom = custom || strings.HasPrefix(spec, rolePath+":")
+	}
+	if !custom {
+		t.fileColors = fileColors
+	}
+	return t, nil
+}
+
+// Returns the color for the path of a file: the LS_COLORS color for the
+// longest suffix of its name that has one, or the path color
+func (t *theme) pathColor(path string) *color.Color {
+	name := strings.ToLower(filepath.Base(path))
+	c, longest := t.path, 0
+	for suffix, fc := range t.fileColors {
+		if len(suffix) > longest && strings.HasSuffix(name, suffix) {
+			c, longest = fc, len(suffix)
+		}
+	}
+	return c
+}
+
+func (t *theme) match(pattern int) *color.Color {
+	return t.matches[pattern]
+}
+
+// The first pattern is colored by "match", the rest by "match2", "match3"...
+func patternRole(i int) string {
+	if i == 0 {
+		return roleMatch
+	}
+	return roleMatch + strconv.Itoa(i+1)
+}
+
+// Parses a "{type}:{attribute}:{value}" or "{type}:none" spec, for example
+// "match:fg:red", "path:style:underline" or "line:bg:208"
+func applyColorSpec(styles map[string]*style, spec string) error {
+	parts := strings.Split(spec, ":")
+
+	s, ok := styles[parts[0]]
+	if !ok {
+		return fmt.Errorf("invalid color spec %q: unknown type %q", spec, parts[0])
+	}
+
+	if len(parts) == 2 && parts[1] == "none" {
+		*s = style{}
+		return nil
+	}
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid color spec %q: expected {type}:{attribute}:{value}", spec)
+	}
+
+	switch attr, value := parts[1], parts[2]; attr {
+	case "fg", "bg":
+		c, err := parseColor(value, attr == "bg")
+		if err != nil {
+			return fmt.Errorf("invalid color spec %q: %v", spec, err)
+		}
+		if attr == "fg" {
+			s.fg = c
+		} else {
+			s.bg = c
+		}
+	case "style":
+		a, ok := styleNames[value]
+		if !ok {
+			return fmt.Errorf("invalid color spec %q: unknown style %q", spec, value)
+		}
+		s.styles = append(s.styles, a)
+	default:
+		return fmt.Errorf("invalid color spec %q: unknown attribute %q", spec, attr)
+	}
+	return nil
+}
+
+// Parses a color name or a 256 color palette number
+func parseColor(value string, bg bool) ([]color.Attribute, error) {
+	if a, ok := colorNames[value]; ok {
+		if bg {
+			// Background colors are offset from their foregrounds by 10
+			a += color.BgBlack - color.FgBlack
+		}
+		return []color.Attribute{a}, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > 255 {
+		return nil, fmt.Errorf("unknown color %q", value)
+	}
+	if bg {
+		return []color.Attribute{48, 5, color.Attribute(n)}, nil
+	}
+	return []color.Attribute{38, 5, color.Attribute(n)}, nil
+}
+
+// Applies a GREP_COLORS value such as "ms=01;31:fn=35:ln=32:se=36". Each
+// capability replaces the whole style of its element with the given SGR
+// parameters. Unsupported capabilities are ignored.
+func applyGrepColors(styles map[string]*style, env string) error {
+	if env == "" {
+		return nil
+	}
+
+	for _, capability := range strings.Split(env, ":") {
+		kv := strings.SplitN(capability, "=", 2)
+		role, ok := grepColorRoles[kv[0]]
+		if !ok || len(kv) != 2 {
+			continue
+		}
+
+		attrs, err := parseSGR(kv[1])
+		if err != nil {
+			return fmt.Errorf("invalid GREP_COLORS capability %q", capability)
+		}
+		*styles[role] = style{styles: attrs}
+	}
+	return nil
+}
+
+// Applies an LS_COLORS value such as "di=01;34:fi=00:*.go=01;32" to file
+// paths. The "fi" entry replaces the path style, and the colors of entries for
+// file name suffixes are returned by suffix. Other entries, and any that
+// aren't valid, are ignored, since the variable is really meant for ls.
+func applyLSColors(styles map[string]*style, env string) map[string]*color.Color {
+	if env == "" {
+		return nil
+	}
+
+	fileColors := make(map[string]*color.Color)
+	for _, entry := range strings.Split(env, ":") {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		attrs, err := parseSGR(kv[1])
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case kv[0] == "fi":
+			*styles[rolePath] = style{styles: attrs}
+		case strings.HasPrefix(kv[0], "*") && len(kv[0]) > 1:
+			// Suffixes match regardless of case, like in GNU ls
+			fileColors[strings.ToLower(kv[0][1:])] = (&style{styles: attrs}).color()
+		}
+	}
+	return fileColors
+}
+
+// Parses semicolon separated SGR parameters, such as "01;31"
+func parseSGR(value string) ([]color.Attribute, error) {
+	var attrs []color.Attribute
+	for _, param := range strings.Split(value, ";") {
+		if param == "" {
+			continue
+		}
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, color.Attribute(n))
+	}
+	return attrs, nil
+}
+
+// Colors s unless c is nil or s is empty
+func paint(c *color.Color, s string) string {
+	if c == nil || s == "" {
+		return s
+	}
+	return c.Sprint(s)
+}
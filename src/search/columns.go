@@ -0,0 +1,139 @@
+package search
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// Lines longer than this are omitted unless --max-columns is given. It's
+	// meant for minified files, so that ordinary long lines are still printed.
+	defaultMaxColumns = 4096
+
+	// Each match previewed in a long line gets a window at least this wide,
+	// so the number of windows is limited to max-columns / minPreviewWidth
+	minPreviewWidth = 40
+
+	ellipsis = "…"
+)
+
+// Writes a line that is longer than --max-columns. By default it's replaced by
+// a note saying how many matches it had. With --max-columns-preview, windows
+// of text centered on its matches are shown instead, fitted into max-columns.
+func (p *printer) writeLongLine(offset int, text []byte, ms []match) {
+	maxColumns := p.ss.maxColumns
+
+	if !p.ss.opts.MaxColumnsPreview {
+		if len(ms) == 0 {
+			p.out.WriteString(paint(p.colors.context, "[Omitted long context line]"))
+		} else {
+			p.out.WriteString("[Omitted long line with " + plural(len(ms), "match", "matches") + "]")
+		}
+		return
+	}
+
+	if len(ms) == 0 {
+		end := runeStart(text, maxColumns)
+		p.writeHighlighted(text, 0, end, offset, nil)
+		p.out.WriteString(ellipsis)
+		return
+	}
+
+	windows, omitted := previewWindows(len(text), maxColumns, offset, ms)
+	last := 0
+	for _, w := range windows {
+		// Avoid splitting multi-byte characters
+		w[0], w[1] = runeStart(text, w[0]), runeStart(text, w[1])
+		if w[0] > last {
+			p.out.WriteString(ellipsis)
+		}
+		p.writeHighlighted(text, w[0], w[1], offset, ms)
+		last = w[1]
+	}
+	if last < len(text) {
+		p.out.WriteString(ellipsis)
+	}
+
+	if omitted > 0 {
+		p.out.WriteString(" [+" + plural(omitted, "more match", "more matches") + "]")
+	}
+}
+
+// previewWindows splits maxColumns between windows centered on the matches of
+// a line of length n. Matches which fall within an earlier window share it.
+// Returns the windows (as start and end offsets into the line) and the number
+// of matches which couldn't be shown.
+func previewWindows(n, maxColumns, offset int, ms []match) (windows [][2]int, omitted int) {
+	numWindows := len(ms)
+	if max := maxColumns / minPreviewWidth; numWindows > max {
+		numWindows = max
+	}
+	if numWindows < 1 {
+		numWindows = 1
+	}
+	width := maxColumns / numWindows
+
+	for _, m := range ms {
+		start, end := m.start-offset, m.end-offset
+		if len(windows) > 0 && end <= windows[len(windows)-1][1] {
+			continue
+		}
+		if len(windows) == numWindows {
+			omitted++
+			continue
+		}
+
+		// Center the window on the match, shifting it to stay within the line
+		ws := (start+end)/2 - width/2
+		if ws+width > n {
+			ws = n - width
+		}
+		if ws < 0 {
+			ws = 0
+		}
+		we := ws + width
+		if we < end {
+			// The match is wider than the window; show as much as fits
+			ws, we = start, start+width
+		}
+
+		// Don't overlap the previous window
+		if len(windows) > 0 {
+			if prev := &windows[len(windows)-1]; ws < prev[1] {
+				ws = prev[1]
+			}
+		}
+
+		windows = append(windows, [2]int{ws, we})
+	}
+
+	return windows, omitted
+}
+
+// Returns the largest i <= n which is the start of a UTF-8 character in text
+func runeStart(text []byte, n int) int {
+	if n >= len(text) {
+		return len(text)
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// extractColumns returns the header for --extract: the path, the line number
+// and a column for each capture group of the patterns. Groups are named after
+// the first pattern which names them, otherwise by number. Patterns without
+// groups get a single column for the whole match.
+func extractColumns(patterns []*pattern) []string {
+	var groups []string
+	for _, p := range patterns {
+		if p.regexp == nil {
+			continue
+		}
+		for i, name := range p.regexp.SubexpNames()[1:] {
+			if i == len(groups) {
+				groups = append(groups, strconv.Itoa(i+1))
+			}
+			if name != "" && groups[i] == strconv.Itoa(i+1) {
+				groups[i] = name
+			}
+		}
+	}
+	if len(groups) == 0 {
+		groups = []string{"match"}
+	}
+	return append([]string{"path", "line"}, groups...)
+}
+
+// Writes the header row for --extract
+func (ss *SuperSearch) printExtractHeader() {
+	var out strings.Builder
+	writeRecord(&out, ss.opts.Extract, extractColumns(ss.patterns))
+	ss.print(out.String())
+}
+
+// Writes a row for each match on a line, with the text of each capture group
+func (p *printer) extractLine(lineNo, offset int, text []byte, ms []match) {
+	numGroups := len(p.ss.extractColumns) - 2
+
+	for i := range ms {
+		m := &ms[i]
+		record := []string{p.path, strconv.Itoa(lineNo)}
+
+		if len(m.groups) == 0 {
+			record = append(record, escapeInvalidUTF8(text[m.start-offset:m.end-offset]))
+		} else {
+			for g := 1; g <= numGroups; g++ {
+				value := ""
+				if start, end := m.group(g); start >= 0 {
+					value = escapeInvalidUTF8(text[start-offset : end-offset])
+				}
+				record = append(record, value)
+			}
+		}
+
+		for len(record) < len(p.ss.extractColumns) {
+			record = append(record, "")
+		}
+		writeRecord(&p.out, p.ss.opts.Extract, record)
+	}
+}
+
+// Writes a CSV or TSV record. CSV fields are quoted as needed. TSV fields
+// can't contain tabs or newlines, so those (and backslashes) are escaped.
+func writeRecord(out *strings.Builder, format string, record []string) {
+	if format == "csv" {
+		w := csv.NewWriter(out)
+		w.Write(record)
+		w.Flush()
+		return
+	}
+
+	escape := strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	for i, field := range record {
+		if i > 0 {
+			out.WriteByte('\t')
+		}
+		out.WriteString(escape.Replace(field))
+	}
+	out.WriteByte('\n')
+}
+
+// Replaces bytes which aren't valid UTF-8 with \xNN escapes, so that they
+// survive being written to CSV
+func escapeInvalidUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+
+	var s strings.Builder
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&s, `\x%02x`, b[0])
+		} else {
+			s.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return s.String()
+}
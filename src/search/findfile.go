@@ -0,0 +1,218 @@
+package search
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Number of paths printed by --find-file, unless --max-results is given
+const defaultFindResults = 20
+
+// Fuzzy match scoring. Every matched character scores, with bonuses for
+// characters that start a path segment, a word or a camelCase hump, and for
+// runs of consecutive characters. Skipped characters between matches cost a
+// little each, so tighter matches win.
+const (
+	scoreMatch       = 16
+	bonusSegment     = 48
+	bonusBoundary    = 32
+	bonusCamel       = 32
+	bonusConsecutive = 24
+	penaltyGap       = 2
+)
+
+// candidate is a file found for --find-file, with the path the query is
+// matched against
+type candidate struct {
+	path string
+	rel  string
+
+	score     int
+	positions []int
+}
+
+// Records a file found in root as a --find-file candidate
+func (ss *SuperSearch) addCandidate(root, path string) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		rel = filepath.Base(path)
+	}
+	ss.candidates = append(ss.candidates, &candidate{path: path, rel: rel})
+}
+
+// Ranks the candidates against the --find-file query and prints the best
+func (ss *SuperSearch) printFoundFiles() {
+	query := []rune(strings.Replace(*ss.opts.FindFile, " ", "", -1))
+
+	// Smart case: the query only ignores case if it's all lowercase
+	ignoreCase := ss.opts.IgnoreCase || strings.ToLower(string(query)) == string(query)
+
+	var ranked []*candidate
+	for _, c := range ss.candidates {
+		var ok bool
+		if c.score, c.positions, ok = fuzzyMatch(query, []rune(c.rel), ignoreCase); ok {
+			ranked = append(ranked, c)
+		}
+	}
+
+	// Shorter paths win ties
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if len(a.rel) != len(b.rel) {
+			return len(a.rel) < len(b.rel)
+		}
+		return a.rel < b.rel
+	})
+
+	limit := int(ss.opts.MaxResults)
+	if limit == 0 {
+		limit = defaultFindResults
+	}
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	if ss.opts.Quiet {
+		return
+	}
+	var out strings.Builder
+	for _, c := range ranked {
+		out.WriteString(ss.highlightPath(c))
+		if ss.opts.Null {
+			out.WriteByte(0)
+		} else {
+			out.WriteByte('\n')
+		}
+	}
+	ss.print(out.String())
+}
+
+// Returns the display path of c with its matched characters highlighted, as
+// long as the display path ends with the one that was matched
+func (ss *SuperSearch) highlightPath(c *candidate) string {
+	display := ss.displayPath(c.path)
+	pathColor := ss.colors.pathColor(display)
+	if !strings.HasSuffix(display, c.rel) {
+		return paint(pathColor, display)
+	}
+
+	var b strings.Builder
+	b.WriteString(paint(pathColor, display[:len(display)-len(c.rel)]))
+	rel := []rune(c.rel)
+	last := 0
+	for _, pos := range c.positions {
+		b.WriteString(paint(pathColor, string(rel[last:pos])))
+		b.WriteString(paint(ss.colors.match(0), string(rel[pos])))
+		last = pos + 1
+	}
+	b.WriteString(paint(pathColor, string(rel[last:])))
+	return b.String()
+}
+
+// fuzzyMatch finds the best scoring way to match query as a subsequence of
+// path, returning its score and the positions of the matched characters. It
+// returns false if query isn't a subsequence of path at all.
+func fuzzyMatch(query, path []rune, ignoreCase bool) (int, []int, bool) {
+	n, m := len(query), len(path)
+	if n == 0 {
+		return 0, nil, true
+	}
+
+	eq := func(a, b rune) bool {
+		return a == b || ignoreCase && unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// Quickly rule out paths that don't contain the query
+	i := 0
+	for j := 0; j < m && i < n; j++ {
+		if eq(query[i], path[j]) {
+			i++
+		}
+	}
+	if i < n {
+		return 0, nil, false
+	}
+
+	// score[i][j] is the best score for matching query[:i+1] with query[i]
+	// at path[j], and from[i][j] the position query[i-1] was matched at
+	const none = -1 << 31
+	score := make([][]int, n)
+	from := make([][]int, n)
+	for i := range score {
+		score[i] = make([]int, m)
+		from[i] = make([]int, m)
+	}
+
+	for i := 0; i < n; i++ {
+		// The best score[i-1][k] for k < j-1, adjusted so that subtracting
+		// the gap penalty up to j gives its score with the gap
+		best, bestK := none, -1
+
+		for j := 0; j < m; j++ {
+			if i > 0 && j >= 2 && score[i-1][j-2] != none {
+				if s := score[i-1][j-2] + penaltyGap*(j-2); s > best {
+					best, bestK = s, j-2
+				}
+			}
+
+			score[i][j] = none
+			if !eq(query[i], path[j]) {
+				continue
+			}
+			s := scoreMatch + charBonus(path, j)
+
+			if i == 0 {
+				score[i][j], from[i][j] = s, -1
+				continue
+			}
+
+			prev, k := none, -1
+			if best != none {
+				prev, k = best-penaltyGap*(j-1), bestK
+			}
+			if j >= 1 && score[i-1][j-1] != none && score[i-1][j-1]+bonusConsecutive > prev {
+				prev, k = score[i-1][j-1]+bonusConsecutive, j-1
+			}
+			if k >= 0 {
+				score[i][j], from[i][j] = prev+s, k
+			}
+		}
+	}
+
+	end := -1
+	for j := 0; j < m; j++ {
+		if score[n-1][j] != none && (end < 0 || score[n-1][j] > score[n-1][end]) {
+			end = j
+		}
+	}
+
+	positions := make([]int, n)
+	for i, j := n-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	return score[n-1][end], positions, true
+}
+
+// Returns the bonus for matching the character at path[j], which depends on
+// the character before it
+func charBonus(path []rune, j int) int {
+	if j == 0 || path[j-1] == '/' || path[j-1] == filepath.Separator {
+		return bonusSegment
+	}
+	prev, r := path[j-1], path[j]
+	switch {
+	case strings.ContainsRune("_-. ", prev):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(r):
+		return bonusCamel
+	case !unicode.IsDigit(prev) && unicode.IsDigit(r):
+		return bonusBoundary
+	}
+	return 0
+}
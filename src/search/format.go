@@ -0,0 +1,153 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placeholders available in --format templates. Anything else inside braces
+// is treated as a capture group number or name.
+var formatFields = map[string]bool{
+	"path":    true,
+	"relpath": true,
+	"line":    true,
+	"col":     true,
+	"offset":  true,
+	"match":   true,
+	"text":    true,
+	"pattern": true,
+	"size":    true,
+}
+
+// formatter writes one line of output per match, according to a template such
+// as "{path}:{line}:{col}: {match}"
+type formatter struct {
+	segments []formatSegment
+}
+
+// formatSegment is either literal text or a placeholder
+type formatSegment struct {
+	literal string
+	field   string
+}
+
+// parseFormat parses a --format template. Backslash escapes (\t, \n, \0 and
+// \\) are expanded, and "{{" and "}}" produce literal braces.
+func parseFormat(template string, patterns []*pattern) (*formatter, error) {
+	var (
+		f       = &formatter{}
+		literal strings.Builder
+	)
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '\\' && i+1 < len(template):
+			i++
+			switch template[i] {
+			case 't':
+				literal.WriteByte('\t')
+			case 'n':
+				literal.WriteByte('\n')
+			case '0':
+				literal.WriteByte(0)
+			default:
+				literal.WriteByte(template[i])
+			}
+
+		case c == '{' && strings.HasPrefix(template[i:], "{{"),
+			c == '}' && strings.HasPrefix(template[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid format %q: unclosed {", template)
+			}
+			field := template[i+1 : i+end]
+			if err := checkFormatField(field, patterns); err != nil {
+				return nil, fmt.Errorf("invalid format %q: %v", template, err)
+			}
+			if literal.Len() > 0 {
+				f.segments = append(f.segments, formatSegment{literal: literal.String()})
+				literal.Reset()
+			}
+			f.segments = append(f.segments, formatSegment{field: field})
+			i += end
+
+		default:
+			literal.WriteByte(c)
+		}
+	}
+
+	if literal.Len() > 0 {
+		f.segments = append(f.segments, formatSegment{literal: literal.String()})
+	}
+	return f, nil
+}
+
+func checkFormatField(field string, patterns []*pattern) error {
+	if formatFields[field] {
+		return nil
+	}
+	if _, err := strconv.Atoi(field); err == nil {
+		return nil
+	}
+	for _, p := range patterns {
+		if p.groupIndex(field) >= 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown placeholder {%v}", field)
+}
+
+// Writes the formatted output for each of the matches on a line
+func (p *printer) formatLine(lineNo, offset int, text []byte, ms []match) {
+	for i := range ms {
+		m := &ms[i]
+		for _, seg := range p.ss.format.segments {
+			if seg.field == "" {
+				p.out.WriteString(seg.literal)
+				continue
+			}
+			p.formatField(seg.field, lineNo, offset, text, m)
+		}
+		p.out.WriteByte('\n')
+	}
+}
+
+func (p *printer) formatField(field string, lineNo, offset int, text []byte, m *match) {
+	switch field {
+	case "path":
+		p.out.WriteString(p.fullPath)
+	case "relpath":
+		p.out.WriteString(p.path)
+	case "line":
+		p.out.WriteString(strconv.Itoa(lineNo))
+	case "col":
+		p.out.WriteString(strconv.Itoa(m.start - offset + 1))
+	case "offset":
+		p.out.WriteString(strconv.Itoa(m.start))
+	case "match":
+		p.out.Write(text[m.start-offset : m.end-offset])
+	case "text":
+		p.out.Write(text)
+	case "pattern":
+		p.out.WriteString(p.ss.patterns[m.pattern].text)
+	case "size":
+		p.out.WriteString(strconv.FormatInt(p.size, 10))
+	default:
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			n = p.ss.patterns[m.pattern].groupIndex(field)
+		}
+		if n < 0 {
+			return
+		}
+		if start, end := m.group(n); start >= 0 {
+			p.out.Write(text[start-offset : end-offset])
+		}
+	}
+}
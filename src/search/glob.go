@@ -0,0 +1,97 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/wellsjo/SuperSearch/src/gitignore"
+)
+
+// globFilter holds the --glob and --iglob patterns for a directory being
+// searched. They have gitignore syntax, but the opposite meaning: a glob
+// includes the files it matches, and a glob starting with ! excludes them.
+type globFilter struct {
+	matcher gitignore.Matcher
+
+	// Whether any globs include files, in which case files matching none of
+	// them aren't searched
+	whitelist bool
+}
+
+// Returns the globs for searching the directory root, which anchored globs
+// such as vendor/** are relative to, or nil if there aren't any
+func (ss *SuperSearch) newGlobFilter(root string) *globFilter {
+	if len(ss.opts.Globs) == 0 && len(ss.opts.IGlobs) == 0 {
+		return nil
+	}
+
+	var domain []string
+	if root != separator {
+		domain = strings.Split(root, separator)[1:]
+	}
+
+	g := &globFilter{matcher: gitignore.NewMatcher(nil)}
+	add := func(glob string, fold bool) {
+		// Invert the gitignore meaning of the pattern
+		if strings.HasPrefix(glob, "!") {
+			glob = glob[1:]
+		} else {
+			glob = "!" + glob
+			g.whitelist = true
+		}
+
+		if fold {
+			g.matcher.AddPatterns([]gitignore.Pattern{
+				foldPattern{gitignore.ParsePattern(strings.ToLower(glob), lower(domain))},
+			})
+		} else {
+			g.matcher.AddPatterns([]gitignore.Pattern{gitignore.ParsePattern(glob, domain)})
+		}
+	}
+
+	// Case sensitive globs come first, so --iglob takes priority
+	for _, glob := range ss.opts.Globs {
+		add(glob, false)
+	}
+	for _, glob := range ss.opts.IGlobs {
+		add(glob, true)
+	}
+	return g
+}
+
+// Returns Include or Exclude for the last glob matching path, or NoMatch
+func (g *globFilter) match(path []string, isDir bool) gitignore.MatchResult {
+	if g == nil {
+		return gitignore.NoMatch
+	}
+
+	ps := g.matcher.Patterns()
+	for i := len(ps) - 1; i >= 0; i-- {
+		if result := ps[i].Match(path, isDir); result != gitignore.NoMatch {
+			return result
+		}
+	}
+
+	// Directories are always traversed, since they may contain files that
+	// are whitelisted
+	if g.whitelist && !isDir {
+		return gitignore.Exclude
+	}
+	return gitignore.NoMatch
+}
+
+// foldPattern matches paths regardless of case, for --iglob
+type foldPattern struct {
+	gitignore.Pattern
+}
+
+func (p foldPattern) Match(path []string, isDir bool) gitignore.MatchResult {
+	return p.Pattern.Match(lower(path), isDir)
+}
+
+func lower(parts []string) []string {
+	lowered := make([]string, len(parts))
+	for i, s := range parts {
+		lowered[i] = strings.ToLower(s)
+	}
+	return lowered
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// report collects the results of a search for --html
+type report struct {
+	mu    sync.Mutex
+	files []*reportFile
+}
+
+type reportFile struct {
+	Path    string
+	Matches int
+	Lines   []reportLine
+}
+
+type reportLine struct {
+	Number   int
+	Context  bool
+	Gap      bool
+	Segments []reportSegment
+}
+
+// reportSegment is a piece of a line which is either a match or plain text
+type reportSegment struct {
+	Text    string
+	Match   bool
+	Pattern int
+}
+
+func (r *report) add(f *reportFile) {
+	r.mu.Lock()
+	r.files = append(r.files, f)
+	r.mu.Unlock()
+}
+
+// Adds a line to the report, split into matched and unmatched segments
+func (f *reportFile) addLine(lineNo, offset int, text []byte, ms []match, gap bool) {
+	l := reportLine{
+		Number:  lineNo,
+		Context: len(ms) == 0,
+		Gap:     gap,
+	}
+
+	last := 0
+	for _, m := range ms {
+		start := clamp(m.start-offset, last, len(text))
+		end := clamp(m.end-offset, start, len(text))
+		if start == end {
+			continue
+		}
+		if start > last {
+			l.Segments = append(l.Segments, reportSegment{Text: string(text[last:start])})
+		}
+		l.Segments = append(l.Segments, reportSegment{
+			Text:    string(text[start:end]),
+			Match:   true,
+			Pattern: m.pattern % len(reportPatternColors),
+		})
+		last = end
+	}
+	if last < len(text) || len(l.Segments) == 0 {
+		l.Segments = append(l.Segments, reportSegment{Text: string(text[last:])})
+	}
+
+	f.Lines = append(f.Lines, l)
+}
+
+// Writes the report as a self-contained HTML page
+func (ss *SuperSearch) writeReport(path string) error {
+	files := ss.report.files
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	patterns := make([]string, len(ss.patterns))
+	for i, p := range ss.patterns {
+		patterns[i] = p.text
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return reportTemplate.Execute(f, map[string]interface{}{
+		"Location":      strings.Join(ss.opts.Locations, " "),
+		"Patterns":      patterns,
+		"Files":         files,
+		"NumMatches":    ss.numMatches,
+		"FilesMatched":  ss.filesMatched,
+		"FilesSearched": ss.filesSearched,
+		"Duration":      ss.duration.Round(time.Millisecond),
+		"Generated":     time.Now().Format(time.RFC1123),
+		"Colors":        reportPatternColors,
+	})
+}
+
+// Background colors for the matches of each pattern
+var reportPatternColors = []template.CSS{
+	"#ffe066",
+	"#8ce99a",
+	"#a5d8ff",
+	"#ffc9c9",
+	"#e5dbff",
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Search results for {{range $i, $p := .Patterns}}{{if $i}}, {{end}}{{$p}}{{end}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #212529; }
+h1 { font-size: 1.4em; }
+code, pre { font-family: SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; }
+table.stats td { padding: 0.1em 1em 0.1em 0; }
+ul.files { columns: 2; }
+details { margin: 1em 0; border: 1px solid #dee2e6; border-radius: 4px; }
+summary { cursor: pointer; padding: 0.5em; background: #f1f3f5; font-family: SFMono-Regular, Menlo, Consolas, monospace; }
+.count { color: #868e96; }
+pre { margin: 0; padding: 0.5em; overflow-x: auto; }
+.context { color: #868e96; }
+.gap { color: #adb5bd; }
+.number { display: inline-block; min-width: 4em; color: #2b8a3e; user-select: none; }
+{{range $i, $c := .Colors}}mark.p{{$i}} { background: {{$c}}; }
+{{end}}</style>
+</head>
+<body>
+<h1>Search results for {{range $i, $p := .Patterns}}{{if $i}}, {{end}}<code>{{$p}}</code>{{end}} in <code>{{.Location}}</code></h1>
+
+<table class="stats">
+<tr><td>Matches</td><td>{{.NumMatches}}</td></tr>
+<tr><td>Files with matches</td><td>{{.FilesMatched}}</td></tr>
+<tr><td>Files searched</td><td>{{.FilesSearched}}</td></tr>
+<tr><td>Time taken</td><td>{{.Duration}}</td></tr>
+<tr><td>Generated</td><td>{{.Generated}}</td></tr>
+</table>
+
+<h2>Files</h2>
+<ul class="files">
+{{range $i, $f := .Files}}<li><a href="#file{{$i}}"><code>{{$f.Path}}</code></a> <span class="count">({{$f.Matches}})</span></li>
+{{end}}</ul>
+
+{{range $i, $f := .Files}}<details id="file{{$i}}" open>
+<summary>{{$f.Path}} <span class="count">({{$f.Matches}})</span></summary>
+<pre>{{range $f.Lines}}{{if .Gap}}<span class="gap">…</span>
+{{end}}<span{{if .Context}} class="context"{{end}}><span class="number">{{.Number}}</span>{{range .Segments}}{{if .Match}}<mark class="p{{.Pattern}}">{{.Text}}</mark>{{else}}{{.Text}}{{end}}{{end}}</span>
+{{end}}</pre>
+</details>
+{{end}}</body>
+</html>
+`))
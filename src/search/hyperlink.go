@@ -0,0 +1,67 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Named formats which can be given to --hyperlink-format
+	hyperlinkAliases = map[string]string{
+		"default":  "file://{host}{path}#{line}",
+		"file":     "file://{host}{path}",
+		"vscode":   "vscode://file{path}:{line}:{column}",
+		"cursor":   "cursor://file{path}:{line}:{column}",
+		"idea":     "idea://open?file={path}&line={line}&column={column}",
+		"macvim":   "mvim://open?url=file://{path}&line={line}",
+		"textmate": "txmt://open?url=file://{path}&line={line}&column={column}",
+	}
+
+	hyperlinkPlaceholder = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// hyperlinker wraps output text in OSC 8 escape sequences, which modern
+// terminals render as clickable links
+type hyperlinker struct {
+	format string
+	host   string
+}
+
+func newHyperlinker(format string) (*hyperlinker, error) {
+	if alias, ok := hyperlinkAliases[format]; ok {
+		format = alias
+	}
+
+	for _, p := range hyperlinkPlaceholder.FindAllString(format, -1) {
+		switch p {
+		case "{host}", "{path}", "{line}", "{column}":
+		default:
+			return nil, fmt.Errorf("invalid hyperlink format %q: unknown placeholder %v", format, p)
+		}
+	}
+	if !strings.Contains(format, "{path}") {
+		return nil, fmt.Errorf("invalid hyperlink format %q: missing {path}", format)
+	}
+
+	host, _ := os.Hostname()
+	return &hyperlinker{
+		format: format,
+		host:   host,
+	}, nil
+}
+
+// Links text to the given position in the file at the absolute path
+func (h *hyperlinker) link(text, path string, line, column int) string {
+	u := strings.NewReplacer(
+		"{host}", h.host,
+		"{path}", (&url.URL{Path: path}).EscapedPath(),
+		"{line}", strconv.Itoa(line),
+		"{column}", strconv.Itoa(column),
+	).Replace(h.format)
+
+	return "\x1b]8;;" + u + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Lines shown around each change with --interactive, unless context was given
+const interactiveContext = 2
+
+const interactiveHelp = `y - replace this match
+n - don't replace this match
+a - replace this and all remaining matches in the file
+q - quit; don't replace this or any remaining matches
+e - enter the text to replace this match with
+? - print help
+`
+
+var (
+	removedColor = color.New(color.FgRed)
+	addedColor   = color.New(color.FgGreen)
+)
+
+// Walks through each change, like git add -p, keeping only those which are
+// confirmed. Nothing is written until every file has been gone through.
+func (ss *SuperSearch) confirmEdits() {
+	in := bufio.NewReader(ss.in)
+
+	for _, e := range ss.edits {
+		var keep []change
+		all := false
+		for i, c := range e.changes {
+			if all {
+				keep = append(keep, c)
+				continue
+			}
+
+			ss.print(ss.showChange(e, c))
+		PROMPT:
+			for {
+				ss.print(fmt.Sprintf("Replace this match (%d/%d) [y,n,a,q,e,?]? ", i+1, len(e.changes)))
+				answer, err := in.ReadString('\n')
+				if err != nil && answer == "" {
+					// Treat the end of input as quitting
+					answer = "q"
+				}
+
+				switch strings.TrimSpace(answer) {
+				case "y":
+					keep = append(keep, c)
+				case "n":
+				case "a":
+					keep = append(keep, c)
+					all = true
+				case "q":
+					e.changes = keep
+					ss.discardRemaining(e)
+					return
+				case "e":
+					ss.print("Replace with: ")
+					text, err := in.ReadString('\n')
+					if err != nil && text == "" {
+						continue
+					}
+					c.text = []byte(strings.TrimRight(text, "\r\n"))
+					keep = append(keep, c)
+				default:
+					ss.print(interactiveHelp)
+					continue
+				}
+				break PROMPT
+			}
+		}
+		e.changes = keep
+	}
+}
+
+// Drops the changes to the files after e, after quitting
+func (ss *SuperSearch) discardRemaining(e *edit) {
+	for i := len(ss.edits) - 1; i >= 0 && ss.edits[i] != e; i-- {
+		ss.edits[i].changes = nil
+	}
+}
+
+// Formats a change with its line number and the lines around it, showing the
+// old lines in red and the new ones in green
+func (ss *SuperSearch) showChange(e *edit, c change) string {
+	before, after := ss.beforeContext, ss.afterContext
+	if before == 0 && after == 0 {
+		before, after = interactiveContext, interactiveContext
+	}
+
+	// The lines the match is on
+	start := bytes.LastIndexByte(e.old[:c.start], '\n') + 1
+	end := len(e.old)
+	if i := bytes.IndexByte(e.old[c.end:], '\n'); i >= 0 {
+		end = c.end + i + 1
+	}
+	lineNo := bytes.Count(e.old[:start], []byte{'\n'}) + 1
+
+	// Widen the range by the context lines
+	from := start
+	for n := 0; n < before && from > 0; n++ {
+		from = bytes.LastIndexByte(e.old[:from-1], '\n') + 1
+	}
+	to := end
+	for n := 0; n < after && to < len(e.old); n++ {
+		if i := bytes.IndexByte(e.old[to:], '\n'); i >= 0 {
+			to += i + 1
+		} else {
+			to = len(e.old)
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%v%v%v\n", paint(ss.colors.pathColor(e.path), ss.displayPath(e.path)), paint(ss.colors.separator, ":"), paint(ss.colors.line, fmt.Sprint(lineNo)))
+	writeDiffLines(&out, ' ', nil, e.old[from:start])
+	writeDiffLines(&out, '-', removedColor, e.old[start:end])
+
+	var replaced []byte
+	replaced = append(replaced, e.old[start:c.start]...)
+	replaced = append(replaced, c.text...)
+	replaced = append(replaced, e.old[c.end:end]...)
+	writeDiffLines(&out, '+', addedColor, replaced)
+
+	writeDiffLines(&out, ' ', nil, e.old[end:to])
+	return out.String()
+}
+
+// Writes each line of text prefixed by kind, as in a unified diff
+func writeDiffLines(w io.Writer, kind byte, c *color.Color, text []byte) {
+	forEachLine(text, func(lineNo, offset int, line []byte) bool {
+		fmt.Fprintln(w, paint(c, string(kind)+string(line)))
+		return true
+	})
+}
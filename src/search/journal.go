@@ -0,0 +1,190 @@
+package search
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/wellsjo/SuperSearch/src/logger"
+)
+
+// journal records the last files rewritten by --write or --interactive, so
+// that --undo can revert them
+type journal struct {
+	Time  time.Time     `json:"time"`
+	Files []journalFile `json:"files"`
+}
+
+type journalFile struct {
+	Path string `json:"path"`
+
+	// SHA-256 hashes of the file before and after it was rewritten
+	Before string `json:"before"`
+	After  string `json:"after"`
+
+	// The changes which turn the rewritten file back into the original
+	Changes []journalChange `json:"changes"`
+}
+
+// journalChange replaces the bytes Start:End of the rewritten file with
+// Original
+type journalChange struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Original []byte `json:"original"`
+}
+
+// Returns the path of the journal, in $XDG_STATE_HOME/ss or ~/.local/state/ss
+func journalPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "ss", "undo.json"), nil
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Saves the journal for the edits that were written, replacing the previous
+// one
+func saveJournal(edits []*edit) error {
+	j := journal{Time: time.Now()}
+	for _, e := range edits {
+		f := journalFile{
+			Path:   e.path,
+			Before: hash(e.old),
+			After:  hash(e.content()),
+		}
+		for _, c := range e.reverse() {
+			f.Changes = append(f.Changes, journalChange{c.start, c.end, c.text})
+		}
+		j.Files = append(j.Files, f)
+	}
+	if len(j.Files) == 0 {
+		return nil
+	}
+
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, buf, 0600)
+}
+
+// Undo reverts the files changed by the last --write or --interactive run.
+// Nothing is reverted if any of them have been modified since.
+func (ss *SuperSearch) Undo() error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return errors.New("nothing to undo")
+	}
+	if err != nil {
+		return err
+	}
+
+	var j journal
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return fmt.Errorf("invalid undo journal %v: %v", path, err)
+	}
+
+	// Check every file before reverting any of them
+	type revert struct {
+		path     string
+		mode     os.FileMode
+		original []byte
+	}
+	var (
+		reverts  []revert
+		modified []string
+	)
+	for _, f := range j.Files {
+		fi, err := os.Stat(f.Path)
+		if err != nil {
+			modified = append(modified, fmt.Sprintf("%v: %v", ss.displayPath(f.Path), err))
+			continue
+		}
+		current, err := ioutil.ReadFile(f.Path)
+		if err != nil {
+			modified = append(modified, fmt.Sprintf("%v: %v", ss.displayPath(f.Path), err))
+			continue
+		}
+
+		switch hash(current) {
+		case f.Before:
+			// Already reverted
+			continue
+		case f.After:
+		default:
+			modified = append(modified, ss.displayPath(f.Path))
+			continue
+		}
+
+		original, err := f.revert(current)
+		if err != nil || hash(original) != f.Before {
+			modified = append(modified, fmt.Sprintf("%v: the journal is corrupt", ss.displayPath(f.Path)))
+			continue
+		}
+		reverts = append(reverts, revert{f.Path, fi.Mode(), original})
+	}
+
+	if len(modified) > 0 {
+		return fmt.Errorf("not undoing the replacements made %v, since these files have been modified:\n  %v",
+			j.Time.Format("Jan 2 15:04:05"), strings.Join(modified, "\n  "))
+	}
+
+	var out bytes.Buffer
+	for _, r := range reverts {
+		if err := writeAtomic(r.path, r.original, r.mode); err != nil {
+			return fmt.Errorf("failed to revert %v: %v", ss.displayPath(r.path), err)
+		}
+		fmt.Fprintf(&out, "Reverted %v\n", paint(ss.colors.pathColor(r.path), ss.displayPath(r.path)))
+	}
+	fmt.Fprintf(&out, "Reverted %v\n", plural(len(reverts), "file", "files"))
+
+	// Each run can only be undone once
+	if err := os.Remove(path); err != nil {
+		logger.Warn("Failed to remove the undo journal: %v", err)
+	}
+	ss.print(out.String())
+	return nil
+}
+
+// Returns the original contents of the file, given its rewritten contents
+func (f *journalFile) revert(current []byte) ([]byte, error) {
+	changes := make([]change, len(f.Changes))
+	last := 0
+	for i, c := range f.Changes {
+		if c.Start < last || c.End < c.Start || c.End > len(current) {
+			return nil, errors.New("change out of range")
+		}
+		changes[i] = change{c.Start, c.End, c.Original}
+		last = c.End
+	}
+	return applyChanges(current, changes), nil
+}
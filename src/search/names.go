@@ -0,0 +1,103 @@
+package search
+
+import (
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Handles a file found while traversing root: its path is matched with
+// --names or --with-names, and its contents are searched unless only names
+// are being matched or it's a --find-file candidate
+func (ss *SuperSearch) found(root *scanRoot, path string, size int64) {
+	if ss.opts.FindFile != nil {
+		atomic.AddUint64(&ss.filesSearched, 1)
+		ss.addCandidate(root.path, path)
+		return
+	}
+
+	if ss.names {
+		ss.matchName(root.path, path)
+		if ss.namesOnly {
+			atomic.AddUint64(&ss.filesSearched, 1)
+			return
+		}
+	}
+	ss.queue(path, size)
+}
+
+// Matches the patterns against the path of a file relative to the root it was
+// found in, or its name when the file is the root, printing it if they match
+func (ss *SuperSearch) matchName(root, path string) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		rel = filepath.Base(path)
+	}
+
+	ms := findAll(ss.patterns, []byte(rel))
+	if len(ms) == 0 {
+		return
+	}
+	atomic.AddUint64(&ss.numMatches, uint64(len(ms)))
+	if ss.trackStats {
+		atomic.AddUint64(&ss.filesMatched, 1)
+	}
+	if ss.opts.Quiet {
+		return
+	}
+
+	p := ss.newPrinter(&searchFile{path: path, index: ss.nextIndex()})
+	defer ss.done(p.index)
+
+	// The matches can be highlighted when the printed path ends with the
+	// one that was matched, which it does unless it's outside the current
+	// directory
+	pathColor := p.colors.pathColor(p.path)
+	text := paint(pathColor, p.path)
+	if strings.HasSuffix(p.path, rel) {
+		shift := len(p.path) - len(rel)
+		var b strings.Builder
+		b.WriteString(paint(pathColor, p.path[:shift]))
+		last := 0
+		for _, m := range ms {
+			b.WriteString(paint(pathColor, rel[last:m.start]))
+			b.WriteString(paint(p.colors.match(m.pattern), rel[m.start:m.end]))
+			last = m.end
+		}
+		b.WriteString(paint(pathColor, rel[last:]))
+		text = b.String()
+	}
+	if p.links != nil {
+		text = p.links.link(text, p.absPath, 1, 1)
+	}
+
+	p.out.WriteString(text)
+
+	// With --with-names, name hits are labeled so they can't be mistaken
+	// for the heading of a file whose contents match
+	if !ss.namesOnly {
+		if ss.opts.Null {
+			p.out.WriteByte(0)
+		} else {
+			p.out.WriteString(paint(p.colors.separator, ":"))
+		}
+		p.out.WriteString(paint(p.colors.line, "name"))
+		p.out.WriteByte('\n')
+	} else if ss.opts.Null {
+		p.out.WriteByte(0)
+	} else {
+		p.out.WriteByte('\n')
+	}
+	p.flush()
+}
+
+// Prints the path of a file that would be searched, for --files
+func (ss *SuperSearch) listFile(path string) {
+	if ss.opts.Quiet {
+		return
+	}
+	p := ss.newPrinter(&searchFile{path: path, index: ss.nextIndex()})
+	p.fileName()
+	p.flush()
+	ss.done(p.index)
+}
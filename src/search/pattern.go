@@ -0,0 +1,165 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/wellsjo/SuperSearch/src/logger"
+)
+
+// pattern is a single search pattern. Patterns containing regex characters
+// are matched with a regexp, everything else with Boyer-Moore.
+type pattern struct {
+	text   string
+	regexp *regexp.Regexp
+	finder *stringFinder
+
+	// Whether text is a literal string, even if it's matched with a regexp
+	literal bool
+}
+
+// Returns the pattern for text, or an error if it isn't a valid regex
+func newPattern(text string, ignoreCase bool) (*pattern, error) {
+	literal := text != "" && !isRegex(text)
+
+	// Boyer-Moore can't search for an empty string or ignore case, but a
+	// regex can
+	if !literal || ignoreCase {
+		expr := text
+		if literal {
+			expr = regexp.QuoteMeta(text)
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		logger.Debug("Using regex search for %q", expr)
+		rgx, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", text, err)
+		}
+		return &pattern{
+			text:    text,
+			regexp:  rgx,
+			literal: literal,
+		}, nil
+	}
+
+	logger.Debug("Using Boyer-Moore string search for %q", text)
+	return &pattern{
+		text:    text,
+		finder:  makeStringFinder(text),
+		literal: true,
+	}, nil
+}
+
+// findAll returns the matches of every pattern in buf, ordered by offset. When
+// the matches of different patterns overlap, the one starting first wins.
+func findAll(patterns []*pattern, buf []byte) []match {
+	if len(patterns) == 1 {
+		return patterns[0].findAll(buf, 0)
+	}
+
+	var all []match
+	for i, p := range patterns {
+		all = append(all, p.findAll(buf, i)...)
+	}
+	if len(all) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].start < all[j].start
+	})
+
+	matches := all[:1]
+	for _, m := range all[1:] {
+		if m.start >= matches[len(matches)-1].end {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// group returns the offsets of capture group n of m (where group 0 is the
+// whole match), or -1 if the group didn't participate in the match
+func (m *match) group(n int) (start, end int) {
+	if n == 0 {
+		return m.start, m.end
+	}
+	if 2*n+1 >= len(m.groups) || m.groups[2*n] < 0 {
+		return -1, -1
+	}
+	return m.groups[2*n], m.groups[2*n+1]
+}
+
+// Returns the index of the named capture group, or -1 if there isn't one
+func (p *pattern) groupIndex(name string) int {
+	if p.regexp == nil {
+		return -1
+	}
+	for i, n := range p.regexp.SubexpNames() {
+		if n == name && n != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// findAll returns the matches in buf, tagged with the pattern index i
+func (p *pattern) findAll(buf []byte, i int) []match {
+	if p.regexp != nil {
+		return p.findRegex(buf, i)
+	}
+	return p.findString(buf, i)
+}
+
+// Regexes are matched line by line so that anchors and character classes
+// never match across newlines
+func (p *pattern) findRegex(buf []byte, i int) []match {
+	var matches []match
+	binary := false
+
+	forEachLine(buf, func(lineNo, offset int, line []byte) bool {
+		ixs := p.regexp.FindAllSubmatchIndex(line, -1)
+
+		// Skip binary files
+		if ixs == nil && len(matches) == 0 && !utf8.Valid(line) {
+			binary = true
+			return false
+		}
+
+		for _, ix := range ixs {
+			m := match{start: offset + ix[0], end: offset + ix[1], pattern: i}
+			if len(ix) > 2 {
+				m.groups = ix
+				for j := range ix {
+					if ix[j] >= 0 {
+						ix[j] += offset
+					}
+				}
+			}
+			matches = append(matches, m)
+		}
+		return true
+	})
+
+	if binary {
+		return nil
+	}
+	return matches
+}
+
+func (p *pattern) findString(buf []byte, i int) []match {
+	ixs := p.finder.findAll(buf)
+	if len(ixs) == 0 {
+		return nil
+	}
+
+	matches := make([]match, len(ixs))
+	for j, ix := range ixs {
+		matches[j] = match{start: ix, end: ix + len(p.text), pattern: i}
+	}
+	return matches
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ageRegexp = regexp.MustCompile(`^(\d+)([smhdw])$`)
+
+	ageUnits = map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	dateLayouts = []string{
+		"2006-01-02",
+		"2006-01-02 15:04",
+		"2006-01-02 15:04:05",
+		time.RFC3339,
+	}
+)
+
+// metaFilter selects the files searched in directories by their metadata,
+// with --newer, --older, --size, --owner and --perm
+type metaFilter struct {
+	// Modification times must be after newer and before older, unless zero
+	newer, older time.Time
+
+	// Inclusive bounds on the size, unless hasSize is false
+	minSize, maxSize int64
+	hasSize          bool
+
+	uid      uint32
+	hasOwner bool
+
+	// How perm is compared: '=' for exactly, '-' for all of its bits, '/'
+	// for any of them, or 0 to not compare it
+	perm     os.FileMode
+	permTest byte
+}
+
+// Builds the filter for the predicates in opts, or returns nil if there
+// aren't any. Relative times are relative to now.
+func newMetaFilter(opts *Options, now time.Time) (*metaFilter, error) {
+	if opts.Newer == "" && opts.Older == "" && opts.Size == "" && opts.Owner == "" && opts.Perm == "" {
+		return nil, nil
+	}
+
+	f := &metaFilter{}
+	var err error
+	if opts.Newer != "" {
+		if f.newer, err = parseTime(opts.Newer, now); err != nil {
+			return nil, fmt.Errorf("invalid --newer: %v", err)
+		}
+	}
+	if opts.Older != "" {
+		if f.older, err = parseTime(opts.Older, now); err != nil {
+			return nil, fmt.Errorf("invalid --older: %v", err)
+		}
+	}
+	if opts.Size != "" {
+		if f.minSize, f.maxSize, err = parseSizeRange(opts.Size); err != nil {
+			return nil, fmt.Errorf("invalid --size: %v", err)
+		}
+		f.hasSize = true
+	}
+	if opts.Owner != "" {
+		if f.uid, err = lookupOwner(opts.Owner); err != nil {
+			return nil, fmt.Errorf("invalid --owner: %v", err)
+		}
+		f.hasOwner = true
+	}
+	if opts.Perm != "" {
+		if f.perm, f.permTest, err = parsePerm(opts.Perm); err != nil {
+			return nil, fmt.Errorf("invalid --perm: %v", err)
+		}
+	}
+	return f, nil
+}
+
+// Reports whether the file fi should be searched
+func (f *metaFilter) match(fi os.FileInfo) bool {
+	if f == nil {
+		return true
+	}
+
+	if !f.newer.IsZero() && !fi.ModTime().After(f.newer) {
+		return false
+	}
+	if !f.older.IsZero() && !fi.ModTime().Before(f.older) {
+		return false
+	}
+	if f.hasSize && (fi.Size() < f.minSize || fi.Size() > f.maxSize) {
+		return false
+	}
+	if f.hasOwner {
+		if uid, ok := fileOwner(fi); !ok || uid != f.uid {
+			return false
+		}
+	}
+
+	perm := fi.Mode().Perm()
+	switch f.permTest {
+	case '=':
+		return perm == f.perm
+	case '-':
+		return perm&f.perm == f.perm
+	case '/':
+		return perm&f.perm != 0
+	}
+	return true
+}
+
+// Parses an age such as 30m, 2d or 1w before now, or a date such as
+// 2026-01-01 or 2026-01-01 15:04 in local time
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if m := ageRegexp.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return now.Add(-time.Duration(n) * ageUnits[m[2]]), nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected an age such as 2d or a date such as 2026-01-01, got %q", s)
+}
+
+// Parses a size like find's -size: +100K means larger than 100 kilobytes,
+// -100K smaller, and 100K a size that rounds up to 100 kilobytes
+func parseSizeRange(s string) (min, max int64, err error) {
+	sign := ""
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		sign, s = s[:1], s[1:]
+	}
+	if s == "" {
+		return 0, 0, fmt.Errorf("expected a size such as +100K")
+	}
+
+	unit := int64(1)
+	if suffix := s[len(s)-1:]; strings.ContainsAny(suffix, "kKmMgG") {
+		unit, _ = parseSize("1" + suffix)
+	}
+	size, err := parseSize(s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch sign {
+	case "+":
+		return size + 1, 1<<63 - 1, nil
+	case "-":
+		return 0, size - 1, nil
+	}
+	return size - unit + 1, size, nil
+}
+
+// Returns the user ID for a user name or ID
+func lookupOwner(owner string) (uint32, error) {
+	u, err := user.Lookup(owner)
+	if err != nil {
+		if u, err = user.LookupId(owner); err != nil {
+			return 0, fmt.Errorf("unknown user %q", owner)
+		}
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("user %q doesn't have a numeric ID", owner)
+	}
+	return uint32(uid), nil
+}
+
+// Parses permissions like find's -perm: 644 for exactly those permissions,
+// -644 for at least all of them and /111 for any of them
+func parsePerm(s string) (os.FileMode, byte, error) {
+	test := byte('=')
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "/") {
+		test, s = s[0], s[1:]
+	}
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, 0, fmt.Errorf("expected octal permissions such as 644, -644 or /111")
+	}
+	return os.FileMode(perm), test, nil
+}
@@ -1,80 +1,393 @@
 package search
 
 import (
-	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync/atomic"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/wellsjo/SuperSearch/src/syntax"
 )
 
 func (ss *SuperSearch) handleMatches(sf *searchFile) {
 	atomic.AddUint64(&ss.numMatches, uint64(len(sf.matches)))
 
-	if ss.opts.Quiet {
+	if ss.opts.Tree && len(sf.matches) > 0 {
+		ss.recordTree(sf, len(sf.matches))
+	}
+
+	p := ss.newPrinter(sf)
+
+	// The results go to the HTML report instead of being printed, so it's
+	// written even with --quiet or --tree
+	if ss.report != nil {
+		if len(sf.matches) == 0 {
+			return
+		}
+		p.report = &reportFile{Path: p.path, Matches: len(sf.matches)}
+		defer ss.report.add(p.report)
+	} else if ss.opts.Tree || ss.opts.Quiet {
 		return
 	}
 
-	fName := strings.Replace(sf.path, ss.workDir, "", -1)
-	if fName[0] == '/' {
-		fName = fName[1:]
+	if ss.opts.FilesWithMatches {
+		if len(sf.matches) > 0 {
+			p.fileName()
+			p.flush()
+		}
+		return
 	}
 
-	var (
-		output strings.Builder
+	ms := sf.matches
+	forEachLine(sf.buf, func(lineNo, offset int, line []byte) bool {
+		// Collect the matches that start on this line
+		end := offset + len(line)
+		n := 0
+		for n < len(ms) && ms[n].start <= end {
+			n++
+		}
+
+		p.emit(lineNo, offset, line, ms[:n])
+		ms = ms[n:]
+		return len(ms) > 0 || p.afterLeft > 0 || ss.opts.Passthru
+	})
 
-		lineNo       = 1
-		matchIndex   = 0
-		lastIndex    = 0
-		printingLine = false
-		done         = false
-	)
+	p.finish()
+}
 
-	output.WriteString(highlightFile.Sprintf("%v\n", fName))
+// emit passes a line to whichever output format is in use
+func (p *printer) emit(lineNo, offset int, text []byte, ms []match) {
+	switch {
+	case p.report != nil:
+		p.line(lineNo, offset, text, ms)
+	case p.ss.format != nil:
+		p.formatLine(lineNo, offset, text, ms)
+	case p.ss.opts.Extract != "":
+		p.extractLine(lineNo, offset, text, ms)
+	default:
+		p.line(lineNo, offset, text, ms)
+	}
+}
 
-	for i := 0; i < len(sf.buf); i++ {
+// printer formats the output for a single file. Output is buffered until
+// flush is called, so that results from concurrent workers don't interleave.
+type printer struct {
+	ss     *SuperSearch
+	colors *theme
+	out    strings.Builder
 
-		if sf.buf[i] == '\n' {
+	// The path as displayed (relative to the working directory if possible),
+	// as it was found, and as an absolute path for hyperlinks
+	path     string
+	fullPath string
+	absPath  string
 
-			// If a match is found on this line, print the rest of the line
-			if printingLine {
-				output.Write(sf.buf[lastIndex:i])
-				if done {
-					break
-				}
-				output.WriteRune('\n')
-				printingLine = false
-			}
+	// Hyperlinks for the path and line numbers; nil when disabled
+	links *hyperlinker
+
+	// When writing an HTML report, lines are added to it instead of output
+	report *reportFile
+
+	// For --syntax, the file's language, the lexer state at the end of the
+	// last line and the tokens of the line being written
+	lang        *syntax.Language
+	syntaxState syntax.State
+	tokens      []syntax.Token
+
+	size int64
+
+	// The output order of the file, for printLoop
+	index uint64
+
+	// Whether the file name heading has been written
+	headed bool
+
+	// Lines kept for before-context, and the number of after-context lines
+	// still to be printed
+	before    []contextLine
+	afterLeft int
+
+	// The last line number written, used to separate non-adjacent context
+	lastLine int
+}
+
+type contextLine struct {
+	lineNo int
+	offset int
+	text   []byte
+	tokens []syntax.Token
+}
+
+func (ss *SuperSearch) newPrinter(sf *searchFile) *printer {
+	p := &printer{
+		ss:       ss,
+		colors:   ss.colors,
+		path:     ss.displayPath(sf.path),
+		fullPath: sf.path,
+		size:     sf.size,
+		index:    sf.index,
+	}
+	if ss.hyperlinks != nil && !sf.stream {
+		p.links = ss.hyperlinks
+		p.absPath, _ = filepath.Abs(sf.path)
+	}
+	return p
+}
 
-			lineNo++
-			lastIndex = i + 1
+// Writes the (possibly hyperlinked) file name in the path color
+func (p *printer) writePath() {
+	path := paint(p.colors.pathColor(p.path), p.path)
+	if p.links != nil {
+		path = p.links.link(path, p.absPath, 1, 1)
+	}
+	p.out.WriteString(path)
+}
+
+// Writes the file name on its own, terminated by a newline or NUL byte
+func (p *printer) fileName() {
+	p.writePath()
+	if p.ss.opts.Null {
+		p.out.WriteByte(0)
+	} else {
+		p.out.WriteByte('\n')
+	}
+}
+
+// line is called with every line of the file, in order, along with the
+// matches on it. Lines containing matches are written, as are the lines
+// around them when context is enabled.
+func (p *printer) line(lineNo, offset int, text []byte, ms []match) {
+	// Every line is tokenized, so that strings and comments spanning lines
+	// are highlighted correctly
+	if p.ss.opts.Syntax {
+		if lineNo == 1 {
+			p.lang = syntax.Detect(p.fullPath, text)
+		}
+		if p.lang != nil {
+			p.tokens, p.syntaxState = p.lang.Tokenize(text, p.syntaxState)
 		}
+	}
 
-		if done {
-			if printingLine {
-				continue
+	if p.ss.opts.Passthru {
+		if p.ss.multipleFiles || p.report != nil {
+			p.writeLine(lineNo, offset, text, ms)
+		} else {
+			p.writeText(offset, text, ms)
+		}
+		return
+	}
+
+	if len(ms) == 0 {
+		if p.afterLeft > 0 {
+			p.afterLeft--
+			p.writeLine(lineNo, offset, text, nil)
+		} else if p.ss.beforeContext > 0 {
+			if len(p.before) == p.ss.beforeContext {
+				p.before = p.before[1:]
 			}
-			break
+			p.before = append(p.before, contextLine{lineNo, offset, text, p.tokens})
 		}
+		return
+	}
 
-		if i == sf.matches[matchIndex] {
-			matchIndex++
+	tokens := p.tokens
+	for _, l := range p.before {
+		p.tokens = l.tokens
+		p.writeLine(l.lineNo, l.offset, l.text, nil)
+	}
+	p.before = p.before[:0]
+	p.tokens = tokens
 
-			// Print line number, followed by each match
-			if !printingLine {
-				output.WriteString(highlightNumber.Sprintf("%v:", lineNo))
-			}
+	p.writeLine(lineNo, offset, text, ms)
+	p.afterLeft = p.ss.afterContext
+}
 
-			output.Write(sf.buf[lastIndex:i])
-			output.WriteString(highlightMatch.Sprint(string(sf.buf[i : i+len(ss.opts.Pattern)])))
+// Writes a single line prefixed by its line number (and the file name when
+// headings are disabled). Matching lines use ':' as their separator and
+// context lines use '-'.
+func (p *printer) writeLine(lineNo, offset int, text []byte, ms []match) {
+	sep := "-"
+	if len(ms) > 0 {
+		sep = ":"
+	}
 
-			if matchIndex == len(sf.matches) {
-				done = true
-			}
+	gap := p.hasContext() && p.lastLine > 0 && lineNo > p.lastLine+1
+	p.lastLine = lineNo
+
+	if p.report != nil {
+		p.report.addLine(lineNo, offset, text, ms, gap)
+		return
+	}
 
-			lastIndex = i + len(ss.opts.Pattern)
-			printingLine = true
+	if gap {
+		p.out.WriteString(paint(p.colors.separator, "--"))
+		p.out.WriteByte('\n')
+	}
+
+	// With --only-matching, each match is written on its own line
+	if p.ss.opts.OnlyMatching && len(ms) > 0 {
+		for i := range ms {
+			p.writePrefix(lineNo, ms[i].start-offset+1, sep)
+			p.out.WriteString(paint(p.colors.match(ms[i].pattern), string(p.matchText(offset, text, &ms[i]))))
+			p.out.WriteByte('\n')
 		}
+		return
+	}
+
+	column := 1
+	if len(ms) > 0 {
+		column = ms[0].start - offset + 1
 	}
+	p.writePrefix(lineNo, column, sep)
+	p.writeText(offset, text, ms)
+}
+
+// Writes the path (when headings are disabled), line number and column that
+// precede the text of each line
+func (p *printer) writePrefix(lineNo, column int, sep string) {
+	if p.ss.opts.NoHeading {
+		p.writePath()
+		if p.ss.opts.Null {
+			p.out.WriteByte(0)
+		} else {
+			p.out.WriteString(paint(p.colors.separator, sep))
+		}
+	} else if !p.headed {
+		p.fileName()
+		p.headed = true
+	}
+
+	number := paint(p.colors.line, strconv.Itoa(lineNo))
+	if p.links != nil {
+		number = p.links.link(number, p.absPath, lineNo, column)
+	}
+	p.out.WriteString(number)
+	p.out.WriteString(paint(p.colors.separator, sep))
+
+	if p.ss.opts.Column && sep == ":" {
+		p.out.WriteString(paint(p.colors.column, strconv.Itoa(column)))
+		p.out.WriteString(paint(p.colors.separator, sep))
+	}
+}
+
+// Writes the text of a line with its matches highlighted
+func (p *printer) writeText(offset int, text []byte, ms []match) {
+	if p.ss.maxColumns > 0 && len(text) > p.ss.maxColumns {
+		p.writeLongLine(offset, text, ms)
+	} else {
+		p.writeHighlighted(text, 0, len(text), offset, ms)
+	}
+	p.out.WriteByte('\n')
+}
+
+// Writes text[from:to], highlighting the parts of it which are covered by
+// matches. Context lines (which have no matches) use the context color, on top
+// of their syntax colors with --syntax.
+func (p *printer) writeHighlighted(text []byte, from, to, offset int, ms []match) {
+	if len(ms) == 0 {
+		if p.lang == nil {
+			p.out.WriteString(paint(p.colors.context, string(text[from:to])))
+		} else {
+			p.writeTokens(text, from, to, p.colors.context, p.colors.contextSyntax)
+		}
+		return
+	}
+
+	last := from
+	for i := range ms {
+		m := &ms[i]
+		start := clamp(m.start-offset, last, to)
+		end := clamp(m.end-offset, start, to)
+
+		// Replacements are shown in full, as long as the match starts
+		// within the text being written
+		if p.ss.replace != nil && start == m.start-offset && start < to {
+			p.writePlain(text, last, start)
+			p.out.WriteString(paint(p.colors.match(m.pattern), string(p.matchText(offset, text, m))))
+			last = end
+			continue
+		}
+
+		if start == end {
+			continue
+		}
+		p.writePlain(text, last, start)
+		p.out.WriteString(paint(p.colors.match(m.pattern), string(text[start:end])))
+		last = end
+	}
+	p.writePlain(text, last, to)
+}
+
+// Writes text[from:to], which contains no matches, colored by syntax when
+// --syntax is enabled
+func (p *printer) writePlain(text []byte, from, to int) {
+	if p.lang == nil {
+		p.out.Write(text[from:to])
+		return
+	}
+	p.writeTokens(text, from, to, nil, p.colors.syntax)
+}
+
+// Writes text[from:to] with the syntax tokens in it colored by kind, and the
+// text between them colored by plain
+func (p *printer) writeTokens(text []byte, from, to int, plain *color.Color, kinds []*color.Color) {
+	for _, t := range p.tokens {
+		start := clamp(t.Start, from, to)
+		end := clamp(t.End, start, to)
+		if start == end {
+			continue
+		}
+		p.out.WriteString(paint(plain, string(text[from:start])))
+		p.out.WriteString(paint(kinds[t.Kind], string(text[start:end])))
+		from = end
+	}
+	p.out.WriteString(paint(plain, string(text[from:to])))
+}
+
+func (p *printer) hasContext() bool {
+	return p.ss.beforeContext > 0 || p.ss.afterContext > 0
+}
+
+// Sends the output buffered so far to printLoop, which prints it once the
+// output of the files before this one has been printed
+func (p *printer) flush() {
+	if p.out.Len() == 0 {
+		return
+	}
+	p.ss.printQueue <- &printFile{output: p.out.String(), index: p.index}
+	p.out.Reset()
+}
+
+// Prints the remaining output, once the whole file has been seen
+func (p *printer) finish() {
+	// Separate each file's matches with an empty line
+	if p.headed {
+		p.out.WriteByte('\n')
+	}
+	p.flush()
+}
+
+func (ss *SuperSearch) print(s string) {
+	ss.outMu.Lock()
+	io.WriteString(ss.out, s)
+	ss.outMu.Unlock()
+}
+
+// Paths inside the working directory are printed relative to it
+func (ss *SuperSearch) displayPath(path string) string {
+	rel, err := filepath.Rel(ss.workDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
 
-	output.WriteString("\n\n")
-	fmt.Print(output.String())
+// Reports whether f is a terminal, as opposed to a pipe, a regular file or a
+// device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
 }
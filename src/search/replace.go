@@ -0,0 +1,129 @@
+package search
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Returns the text to show for a match: the matched text itself, or its
+// replacement with --replace
+func (p *printer) matchText(offset int, text []byte, m *match) []byte {
+	if p.ss.replace == nil {
+		return text[m.start-offset : m.end-offset]
+	}
+	return p.ss.expand(nil, offset, text, m)
+}
+
+// expand appends the replacement for m to dst, in the case of the match with
+// --preserve-case
+func (ss *SuperSearch) expand(dst []byte, offset int, text []byte, m *match) []byte {
+	if !ss.opts.PreserveCase {
+		return ss.expandTemplate(dst, offset, text, m)
+	}
+	replacement := ss.expandTemplate(nil, offset, text, m)
+	return append(dst, matchCase(text[m.start-offset:m.end-offset], replacement)...)
+}
+
+// For regex patterns, $1 and ${name} in the template are replaced by capture
+// groups, and $$ by a literal $. Literal patterns are replaced by the template
+// as-is.
+func (ss *SuperSearch) expandTemplate(dst []byte, offset int, text []byte, m *match) []byte {
+	p := ss.patterns[m.pattern]
+	if p.literal {
+		return append(dst, *ss.replace...)
+	}
+
+	groups := m.groups
+	if groups == nil {
+		groups = []int{m.start, m.end}
+	}
+
+	// Expand wants group offsets relative to text
+	rel := make([]int, len(groups))
+	for i, g := range groups {
+		rel[i] = g
+		if g >= 0 {
+			rel[i] -= offset
+		}
+	}
+	return p.regexp.Expand(dst, []byte(*ss.replace), text, rel)
+}
+
+// The case shapes recognized by --preserve-case
+const (
+	caseOther = iota
+	caseLower
+	caseUpper
+	// "Title", where only the first letter is uppercase
+	caseTitle
+	// "camelCase" and "PascalCase"
+	caseCamel
+	casePascal
+)
+
+// Returns the case shape of the letters in text
+func caseOf(text []byte) int {
+	var first rune
+	letters, upper, lower := 0, 0, 0
+	for _, r := range string(text) {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if letters == 0 {
+			first = r
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+
+	switch {
+	case letters == 0:
+		return caseOther
+	case upper == 0:
+		return caseLower
+	case lower == 0 && letters > 1:
+		return caseUpper
+	case unicode.IsUpper(first) && upper == 1:
+		return caseTitle
+	case unicode.IsUpper(first):
+		return casePascal
+	default:
+		return caseCamel
+	}
+}
+
+// matchCase returns replacement in the case shape of match. Replacements for
+// camelCase and PascalCase matches only have their first letter changed, so
+// they keep their own word boundaries.
+func matchCase(match, replacement []byte) []byte {
+	switch caseOf(match) {
+	case caseLower:
+		return bytes.ToLower(replacement)
+	case caseUpper:
+		return bytes.ToUpper(replacement)
+	case caseTitle:
+		return withFirstLetter(bytes.ToLower(replacement), unicode.ToUpper)
+	case casePascal:
+		return withFirstLetter(replacement, unicode.ToUpper)
+	case caseCamel:
+		return withFirstLetter(replacement, unicode.ToLower)
+	}
+	return replacement
+}
+
+// Maps the first letter of text with fn
+func withFirstLetter(text []byte, fn func(rune) rune) []byte {
+	for i, r := range string(text) {
+		if unicode.IsLetter(r) {
+			out := append([]byte{}, text[:i]...)
+			out = append(out, string(fn(r))...)
+			return append(out, text[i+utf8.RuneLen(r):]...)
+		}
+	}
+	return text
+}
@@ -0,0 +1,227 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wellsjo/SuperSearch/src/diff"
+	"github.com/wellsjo/SuperSearch/src/logger"
+)
+
+// Lines of context around each change with --diff
+const diffContext = 3
+
+var errChanged = errors.New("file changed during the search")
+
+// edit is a pending rewrite of a file, for --write and --diff
+type edit struct {
+	index   uint64
+	path    string
+	mode    os.FileMode
+	modTime time.Time
+	old     []byte
+	changes []change
+}
+
+// change replaces old[start:end] with text
+type change struct {
+	start, end int
+	text       []byte
+}
+
+// Returns the contents of the file with its changes made
+func (e *edit) content() []byte {
+	return applyChanges(e.old, e.changes)
+}
+
+// Returns text with changes made, which must be in order and not overlap
+func applyChanges(text []byte, changes []change) []byte {
+	var buf []byte
+	last := 0
+	for _, c := range changes {
+		buf = append(buf, text[last:c.start]...)
+		buf = append(buf, c.text...)
+		last = c.end
+	}
+	return append(buf, text[last:]...)
+}
+
+// Returns the changes which turn the contents of the file with its changes
+// made back into the original
+func (e *edit) reverse() []change {
+	var changes []change
+	shift := 0
+	for _, c := range e.changes {
+		start := c.start + shift
+		changes = append(changes, change{start, start + len(c.text), e.old[c.start:c.end]})
+		shift += len(c.text) - (c.end - c.start)
+	}
+	return changes
+}
+
+// Converts changes to the form the diff package takes
+func diffChanges(changes []change) []diff.Change {
+	dcs := make([]diff.Change, len(changes))
+	for i, c := range changes {
+		dcs[i] = diff.Change{Start: c.start, End: c.end, Text: c.text}
+	}
+	return dcs
+}
+
+// Records the replacement of each match in sf, to be written (or diffed)
+// once the search is done. Matches that the replacement leaves as they are
+// aren't counted as changes.
+func (ss *SuperSearch) recordEdit(sf *searchFile, fi os.FileInfo) {
+	var changes []change
+	for i := range sf.matches {
+		m := &sf.matches[i]
+		text := ss.expand(nil, 0, sf.buf, m)
+		if !bytes.Equal(text, sf.buf[m.start:m.end]) {
+			changes = append(changes, change{m.start, m.end, text})
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	ss.editsMu.Lock()
+	ss.edits = append(ss.edits, &edit{
+		index:   sf.index,
+		path:    sf.path,
+		mode:    fi.Mode(),
+		modTime: fi.ModTime(),
+		old:     sf.buf,
+		changes: changes,
+	})
+	ss.editsMu.Unlock()
+}
+
+// Applies the recorded edits in the order the files were found. With --diff
+// a unified diff is printed instead of changing any files, and with
+// --interactive each change is confirmed first.
+func (ss *SuperSearch) applyEdits() {
+	sort.Slice(ss.edits, func(i, j int) bool {
+		return ss.edits[i].index < ss.edits[j].index
+	})
+
+	// The hunks are made from the changes themselves, rather than by diffing
+	// the files, which would be slow for big files with many changes
+	if ss.opts.Diff {
+		for _, e := range ss.edits {
+			ss.print(diff.Unified(diffName("a", ss.displayPath(e.path)), diffName("b", ss.displayPath(e.path)), e.old, diffChanges(e.changes), diffContext))
+		}
+		return
+	}
+
+	if ss.opts.Interactive {
+		ss.confirmEdits()
+	}
+
+	var (
+		out          strings.Builder
+		written      []*edit
+		replacements int
+
+		// With --follow a file can be found through links as well as
+		// directly, but it's only rewritten once
+		targets = make(map[string]bool)
+	)
+	for _, e := range ss.edits {
+		if len(e.changes) == 0 {
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(e.path); err == nil {
+			if targets[target] {
+				continue
+			}
+			targets[target] = true
+		}
+		if err := writeEdit(e); err != nil {
+			logger.Warn("Skipping %v: %v", e.path, err)
+			continue
+		}
+		written = append(written, e)
+		replacements += len(e.changes)
+		fmt.Fprintf(&out, "%v: %v\n", paint(ss.colors.pathColor(e.path), ss.displayPath(e.path)), plural(len(e.changes), "replacement", "replacements"))
+	}
+	fmt.Fprintf(&out, "Replaced %v in %v\n", plural(replacements, "match", "matches"), plural(len(written), "file", "files"))
+	ss.print(out.String())
+
+	// Only the files that were written can be undone
+	if err := saveJournal(written); err != nil {
+		logger.Warn("Failed to save the undo journal, so these replacements can't be undone: %v", err)
+	}
+}
+
+// Paths in diffs are prefixed with a/ and b/, which git apply strips. Paths
+// outside the working directory are absolute, and left without a prefix.
+func diffName(prefix, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return prefix + "/" + path
+}
+
+// writeEdit atomically replaces the contents of a file, unless it has changed
+// since it was searched
+func writeEdit(e *edit) error {
+	fi, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	if fi.Size() != int64(len(e.old)) || !fi.ModTime().Equal(e.modTime) {
+		return errChanged
+	}
+
+	// Modification times can be too coarse to notice quick changes
+	current, err := ioutil.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, e.old) {
+		return errChanged
+	}
+
+	return writeAtomic(e.path, e.content(), e.mode)
+}
+
+// Replaces the contents of a file by writing a temporary file next to it and
+// renaming it over the original, so that it's never left half written. Links
+// are left as they are, and their targets are replaced instead.
+func writeAtomic(path string, content []byte, mode os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".ss-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode.Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
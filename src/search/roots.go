@@ -0,0 +1,223 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/wellsjo/SuperSearch/src/logger"
+)
+
+// root is a file or directory to search, as given on the command line or by
+// --files-from
+type root struct {
+	// The path as given, and its absolute form
+	name string
+	path string
+	info os.FileInfo
+}
+
+// Returns the roots to search in the order given, leaving out duplicates and
+// any that are inside another root. Paths that don't exist are fatal when
+// given as arguments, but only skipped with a warning when listed by
+// --files-from, since lists like `git diff --name-only` include deleted files.
+func (ss *SuperSearch) resolveRoots() []root {
+	var roots []root
+	add := func(name string, listed bool) {
+		if name == stdinPath {
+			roots = append(roots, root{name: name, path: name})
+			return
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			if listed {
+				logger.Warn("Skipping %v: %v", name, err)
+				return
+			}
+			logger.Fail("invalid location input %v", name)
+		}
+
+		// Gitignore matching works on absolute paths
+		path, err := filepath.Abs(name)
+		if err != nil {
+			logger.Fail(err.Error())
+		}
+		roots = append(roots, root{name: name, path: path, info: fi})
+	}
+
+	for _, name := range ss.opts.Locations {
+		add(name, false)
+	}
+	if ss.opts.FilesFrom != "" {
+		names, err := readFileList(ss.opts.FilesFrom)
+		if err != nil {
+			logger.Fail(err.Error())
+		}
+		for _, name := range names {
+			add(name, true)
+		}
+	}
+
+	var unique []root
+	for i, r := range roots {
+		if !coveredBy(r, roots[:i], true) && !coveredBy(r, roots[i+1:], false) {
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
+
+// Reports whether r is one of roots, or is inside one of the directories
+func coveredBy(r root, roots []root, orEqual bool) bool {
+	for _, o := range roots {
+		if r.path == o.path {
+			if orEqual {
+				return true
+			}
+			continue
+		}
+		if o.info != nil && o.info.IsDir() && strings.HasPrefix(r.path, o.path+separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads the newline separated paths in the file at path, or stdin for -
+func readFileList(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != stdinPath {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if name := strings.TrimRight(scanner.Text(), "\r"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// Sets the root of the --tree summary: the root being searched, or the
+// deepest directory containing all of them
+func (ss *SuperSearch) setTreeRoot(roots []root) {
+	if len(roots) == 1 {
+		ss.treeRoot, ss.treeName = roots[0].path, roots[0].name
+		if roots[0].path == stdinPath {
+			ss.treeName = stdinLabel
+		}
+		return
+	}
+
+	var common string
+	for _, r := range roots {
+		if r.info == nil {
+			continue
+		}
+		dir := r.path
+		if !r.info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		if common == "" {
+			common = dir
+			continue
+		}
+		for common != dir && !strings.HasPrefix(dir, common+separator) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				break
+			}
+			common = parent
+		}
+	}
+	ss.treeRoot, ss.treeName = common, ss.displayPath(common)
+}
+
+// scanRoot holds the settings for scanning a directory being searched
+type scanRoot struct {
+	path  string
+	globs *globFilter
+
+	// The file system the root is on, with --one-file-system
+	device    uint64
+	oneDevice bool
+}
+
+func (ss *SuperSearch) newScanRoot(r root) *scanRoot {
+	s := &scanRoot{path: r.path, globs: ss.newGlobFilter(r.path)}
+	if ss.opts.OneFileSystem {
+		s.device, s.oneDevice = deviceID(r.info)
+	}
+	return s
+}
+
+// Reports whether the directory fi is on the same file system as the root,
+// when that matters
+func (s *scanRoot) contains(fi os.FileInfo) bool {
+	if !s.oneDevice {
+		return true
+	}
+	device, ok := deviceID(fi)
+	return !ok || device == s.device
+}
+
+// Parses a size such as 512, 100K, 10M or 1G, with binary multiples
+func parseSize(size string) (int64, error) {
+	multiple := int64(1)
+	switch strings.ToUpper(size[len(size)-1:]) {
+	case "K":
+		multiple = 1 << 10
+	case "M":
+		multiple = 1 << 20
+	case "G":
+		multiple = 1 << 30
+	}
+	if multiple > 1 {
+		size = size[:len(size)-1]
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a number of bytes with an optional K, M or G suffix")
+	}
+	return n * multiple, nil
+}
+
+// Records that the directory at path is being scanned, returning false if it
+// already has been. This only matters with --follow, since links are the only
+// way to reach a directory twice or loop back to one of its parents.
+func (ss *SuperSearch) visit(path string, fi os.FileInfo) bool {
+	if !ss.opts.Follow {
+		return true
+	}
+
+	key, err := fileKey(path, fi)
+	if err != nil {
+		logger.Warn("Skipping %v: %v", path, err)
+		return false
+	}
+	if ss.visited == nil {
+		ss.visited = make(map[interface{}]bool)
+	}
+	if ss.visited[key] {
+		return false
+	}
+	ss.visited[key] = true
+	return true
+}
+
+func resolvedPath(path string) (interface{}, error) {
+	return filepath.EvalSymlinks(path)
+}
@@ -1,13 +1,20 @@
 package search
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/user"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,10 +65,56 @@ func createSearchFile(dir, file string, lines int) {
 	}
 }
 
+// Creates a temporary directory containing files, which maps paths relative
+// to it to their contents. It's removed when the test finishes.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ss-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, contents := range files {
+		writeFile(t, filepath.Join(dir, name), contents)
+	}
+	return dir
+}
+
+// Writes a file, creating the directories it's in if needed
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, oldname, newname string) {
+	t.Helper()
+	if err := os.Symlink(oldname, newname); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Runs a search without colors (unless opts sets them) and returns its output,
+// with dir removed from the start of the paths in it
+func runSearch(dir string, opts *Options) string {
+	if opts.Color == "" {
+		opts.Color = "never"
+	}
+	var out bytes.Buffer
+	s := New(opts)
+	s.out = &out
+	s.Run()
+	return strings.Replace(out.String(), dir+string(filepath.Separator), "", -1)
+}
+
 func TestSearch(t *testing.T) {
 	s := New(&Options{
 		Pattern:      "fox",
-		Location:     testDir,
+		Locations:    []string{testDir},
 		Quiet:        true,
 		Unrestricted: true,
 		ShowStats:    true,
@@ -71,12 +124,858 @@ func TestSearch(t *testing.T) {
 		fmt.Sprintf("there should be %d matches", numFiles1*linesPerFile1))
 }
 
+func TestPipeOutput(t *testing.T) {
+	var out bytes.Buffer
+	s := New(&Options{
+		Pattern:          "fox",
+		Locations:        []string{testDir},
+		Unrestricted:     true,
+		Color:            "never",
+		FilesWithMatches: true,
+		Null:             true,
+	})
+	s.out = &out
+	s.Run()
+	paths := strings.Split(strings.TrimSuffix(out.String(), "\x00"), "\x00")
+	assert.Equal(t, numFiles1, len(paths))
+
+	out.Reset()
+	s = New(&Options{
+		Pattern:      "fox",
+		Locations:    []string{testDir},
+		Unrestricted: true,
+		Color:        "never",
+		NoHeading:    true,
+	})
+	s.out = &out
+	s.Run()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Equal(t, numFiles1*linesPerFile1, len(lines))
+	for _, line := range lines {
+		assert.True(t, strings.HasPrefix(line, testDir), line)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	assert.False(t, isTerminal(devNull))
+}
+
+func TestContext(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"context.txt": "a\nb\nfoo\nc\nd\ne\nf\nbar\ng\n"})
+
+	assert.Equal(t, "context.txt\n2-b\n3:1:foo\n4-c\n--\n7-f\n8:1:bar\n9-g\n\n", runSearch(dir, &Options{
+		Patterns:  []string{"foo", "bar"},
+		Locations: []string{filepath.Join(dir, "context.txt")},
+		Context:   1,
+		Column:    true,
+	}))
+}
+
+func TestInvalidPattern(t *testing.T) {
+	_, err := newPattern("foo(", false)
+	assert.EqualError(t, err, "invalid pattern \"foo(\": error parsing regexp: missing closing ): `foo(`")
+	_, err = newPattern("foo(bar)?", true)
+	assert.NoError(t, err)
+}
+
+func TestColorSpecs(t *testing.T) {
+	// The environment is restored when the test finishes
+	t.Setenv("GREP_COLORS", "")
+	t.Setenv("LS_COLORS", "")
+
+	colors, err := newTheme([]string{"match:fg:red", "match2:bg:21", "path:none", "line:style:underline"}, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, colors.path)
+	assert.Equal(t, color.New(color.FgRed, color.BgYellow, color.Bold), colors.match(0))
+	assert.Equal(t, color.New(color.FgBlack, 48, 5, 21, color.Bold), colors.match(1))
+	assert.Equal(t, color.New(color.FgGreen, color.Bold, color.Underline), colors.line)
+
+	t.Setenv("GREP_COLORS", "ms=01;31:fn=35:sl=")
+	colors, err = newTheme([]string{"path:style:bold"}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, color.New(color.Bold, color.FgRed), colors.match(0))
+	assert.Equal(t, color.New(color.FgMagenta, color.Bold), colors.path)
+
+	_, err = newTheme([]string{"match:fg:chartreuse"}, 1)
+	assert.NotNil(t, err)
+
+	// LS_COLORS colors paths by their longest suffix, unless paths are given
+	// a color of their own
+	t.Setenv("GREP_COLORS", "")
+	t.Setenv("LS_COLORS", "di=01;34:ln=target:*.gz=31:*.tar.gz=01;33:*.go=32")
+	colors, err = newTheme(nil, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, color.New(color.FgGreen), colors.pathColor("src/main.go"))
+	assert.Equal(t, color.New(color.Bold, color.FgYellow), colors.pathColor("dist/App.TAR.GZ"))
+	assert.Equal(t, colors.path, colors.pathColor("Makefile"))
+	colors, err = newTheme([]string{"path:fg:red"}, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, color.New(color.FgRed, color.Bold), colors.pathColor("src/main.go"))
+}
+
+func TestHyperlinks(t *testing.T) {
+	h, err := newHyperlinker("vscode")
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b]8;;vscode://file/src/a%20b.go:3:7\x1b\\3\x1b]8;;\x1b\\",
+		h.link("3", "/src/a b.go", 3, 7))
+
+	_, err = newHyperlinker("file://{host}{path}#{lineno}")
+	assert.NotNil(t, err)
+	_, err = newHyperlinker("vscode://file")
+	assert.NotNil(t, err)
+
+	dir := writeFiles(t, map[string]string{"a.txt": "a\nb foo\n"})
+	path := filepath.Join(dir, "a.txt")
+
+	// Links are only made for terminals, so they're enabled after New
+	var out bytes.Buffer
+	s := New(&Options{Pattern: "foo", Locations: []string{path}, Color: "never", NoHeading: true})
+	s.out = &out
+	s.hyperlinks, _ = newHyperlinker("default")
+	s.Run()
+	link := "file://" + s.hyperlinks.host + path
+	assert.Equal(t, "\x1b]8;;"+link+"#1\x1b\\"+path+"\x1b]8;;\x1b\\:"+
+		"\x1b]8;;"+link+"#2\x1b\\2\x1b]8;;\x1b\\:b foo\n", out.String())
+}
+
+func TestSyntaxContext(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"main.go": "n := 1\nfox := 2\n"})
+	out := runSearch(dir, &Options{
+		Pattern:   "fox",
+		Locations: []string{dir},
+		Color:     "always",
+		Colors:    []string{"path:none", "line:none", "separator:none", "context:style:faint"},
+		NoHeading: true,
+		Context:   1,
+		Syntax:    true,
+	})
+
+	// The context style is layered over the syntax colors of context lines
+	faint, match := color.New(color.Faint), color.New(color.FgBlack, color.BgYellow, color.Bold)
+	assert.Equal(t, "main.go-1-"+faint.Sprint("n := ")+color.New(color.FgCyan, color.Faint).Sprint("1")+"\n"+
+		"main.go:2:"+match.Sprint("fox")+" := "+color.New(color.FgCyan).Sprint("2")+"\n", out)
+}
+
+func TestFormat(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.conf": "timeout=30 retries=2\nname=x\ntimeout=5\n"})
+	path := filepath.Join(dir, "app.conf")
+
+	var out bytes.Buffer
+	s := New(&Options{
+		Pattern:   `timeout=(?P<secs>\d+)`,
+		Locations: []string{path},
+		Format:    `{line}:{col}:{offset}\t{match} {1} {secs} {{{size}}}`,
+	})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, "1:1:0\ttimeout=30 30 30 {38}\n3:1:28\ttimeout=5 5 5 {38}\n", out.String())
+
+	_, err := parseFormat("{path}:{nope}", s.patterns)
+	assert.NotNil(t, err)
+}
+
+func TestMaxColumns(t *testing.T) {
+	long := strings.Repeat("a", 100) + "fox" + strings.Repeat("b", 100) + "fox" + strings.Repeat("c", 100)
+	dir := writeFiles(t, map[string]string{"bundle.min.js": long + "\nfox\n"})
+	columns := func(n uint) *uint { return &n }
+
+	assert.Equal(t, "bundle.min.js:1:[Omitted long line with 2 matches]\nbundle.min.js:2:fox\n", runSearch(dir, &Options{
+		Pattern:    "fox",
+		Locations:  []string{dir},
+		NoHeading:  true,
+		MaxColumns: columns(80),
+	}))
+
+	assert.Equal(t, "bundle.min.js:1:…"+strings.Repeat("a", 29)+"fox"+strings.Repeat("b", 28)+"… [+1 more match]\nbundle.min.js:2:fox\n", runSearch(dir, &Options{
+		Pattern:           "fox",
+		Locations:         []string{dir},
+		NoHeading:         true,
+		MaxColumns:        columns(60),
+		MaxColumnsPreview: true,
+	}))
+
+	// By default only lines as long as those of minified files are omitted,
+	// even with --passthru
+	prose := strings.Repeat("a long line of prose ", 10) + "fox"
+	minified := strings.Repeat("x", defaultMaxColumns) + "fox"
+	dir = writeFiles(t, map[string]string{"README.md": prose + "\n" + minified + "\n"})
+	assert.Equal(t, "README.md:1:"+prose+"\nREADME.md:2:[Omitted long line with 1 match]\n",
+		runSearch(dir, &Options{Pattern: "fox", Locations: []string{dir}, NoHeading: true}))
+	assert.Equal(t, prose+"\n[Omitted long line with 1 match]\n",
+		runSearch(dir, &Options{Pattern: "fox", Locations: []string{filepath.Join(dir, "README.md")}, Passthru: true}))
+	assert.Equal(t, "README.md:1:"+prose+"\nREADME.md:2:"+minified+"\n",
+		runSearch(dir, &Options{Pattern: "fox", Locations: []string{dir}, NoHeading: true, MaxColumns: columns(0)}))
+
+	windows, omitted := previewWindows(len(long), 60, 0, []match{{start: 100, end: 103}, {start: 203, end: 206}})
+	assert.Equal(t, 1, omitted)
+	assert.Equal(t, [][2]int{{71, 131}}, windows)
+	windows, omitted = previewWindows(len(long), 100, 0, []match{{start: 100, end: 103}, {start: 203, end: 206}})
+	assert.Equal(t, 0, omitted)
+	assert.Equal(t, [][2]int{{76, 126}, {179, 229}}, windows)
+}
+
+func TestPassthru(t *testing.T) {
+	var out bytes.Buffer
+	s := New(&Options{
+		Patterns:  []string{"ERROR", "WA.N"},
+		Locations: []string{"-"},
+		Color:     "always",
+		Passthru:  true,
+	})
+	s.out = &out
+	s.in = strings.NewReader("a ERROR\nb\nWARN c\n")
+	s.Run()
+
+	colors := s.colors
+	assert.Equal(t, "a "+colors.match(0).Sprint("ERROR")+"\nb\n"+colors.match(1).Sprint("WARN")+" c\n", out.String())
+
+	// Files without matches are printed too, and labeled when there are
+	// several of them
+	dir := writeFiles(t, map[string]string{"a.txt": "one\ntwo\n", "b.txt": "ERROR\nok\n"})
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	assert.Equal(t, "one\ntwo\n", runSearch(dir, &Options{Pattern: "ERROR", Locations: []string{a}, Passthru: true}))
+	assert.Equal(t, "a.txt\n1-one\n2-two\n\nb.txt\n1:ERROR\n2-ok\n\n",
+		runSearch(dir, &Options{Pattern: "ERROR", Locations: []string{a, b}, Passthru: true}))
+}
+
+func TestPipedStdin(t *testing.T) {
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+
+	devNull, _ := os.Open(os.DevNull)
+	defer devNull.Close()
+	os.Stdin = devNull
+	assert.False(t, PipedStdin())
+
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	os.Stdin = r
+	assert.True(t, PipedStdin())
+
+	go func() {
+		w.Write([]byte("ok\nERROR one\n"))
+		w.Write([]byte("ERROR two\n"))
+		w.Close()
+	}()
+
+	var out bytes.Buffer
+	s := New(&Options{Pattern: "ERROR", Locations: []string{"-"}, Color: "never", NoHeading: true})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, "<stdin>:2:ERROR one\n<stdin>:3:ERROR two\n", out.String())
+}
+
+func TestMultiplePaths(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a/x.txt":     "foo 1\n",
+		"a/sub/y.txt": "foo 2\n",
+		"b/z.txt":     "foo 3\n",
+	})
+	run := func(opts *Options) string {
+		opts.Pattern = "foo"
+		opts.NoHeading = true
+		return runSearch(dir, opts)
+	}
+
+	// Paths inside other paths are only searched once, and results are
+	// printed in the order the paths were given
+	in := func(names ...string) []string {
+		for i, n := range names {
+			names[i] = filepath.Join(dir, n)
+		}
+		return names
+	}
+	assert.Equal(t, "b/z.txt:1:foo 3\na/sub/y.txt:1:foo 2\na/x.txt:1:foo 1\n",
+		run(&Options{Locations: in("b/z.txt", "a/sub", "a", "a/x.txt", "b/z.txt")}))
+
+	list := filepath.Join(dir, "list")
+	writeFile(t, list, strings.Join(in("a/x.txt", "deleted.txt", "b/z.txt"), "\n")+"\n")
+	assert.Equal(t, "a/x.txt:1:foo 1\nb/z.txt:1:foo 3\n", run(&Options{FilesFrom: list}))
+
+	assert.Equal(t, `. (2)
+├── a (1)
+│   └── sub (1)
+│       └── y.txt (1)
+└── b (1)
+    └── z.txt (1)
+`, strings.Replace(run(&Options{Locations: in("a/sub", "b"), Tree: true}), dir, ".", 1))
+}
+
+// Returns the files in dir that contain foo
+func filesWithFoo(dir string, opts *Options) string {
+	opts.Pattern = "foo"
+	opts.Locations = []string{dir}
+	opts.FilesWithMatches = true
+	return runSearch(dir, opts)
+}
+
+func TestTypes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.go":      "foo\n",
+		"main_test.go": "foo\n",
+		"app.js":       "foo\n",
+		"Makefile":     "foo\n",
+		"api.proto":    "foo\n",
+		"notes.txt":    "foo\n",
+	})
+	run := func(opts *Options) string { return filesWithFoo(dir, opts) }
+
+	assert.Equal(t, "main.go\n", run(&Options{Types: []string{"go"}, TypesNot: []string{"gotest"}}))
+	assert.Equal(t, "Makefile\napp.js\n", run(&Options{Types: []string{"make", "js"}}))
+	assert.Equal(t, "Makefile\napi.proto\nmain.go\nmain_test.go\n", run(&Options{TypesNot: []string{"js", "txt"}}))
+	assert.Equal(t, "api.proto\nnotes.txt\n", run(&Options{TypeAdd: []string{"proto:*.proto", "notes:notes.*"}, Types: []string{"proto", "notes"}}))
+
+	var out bytes.Buffer
+	s := New(&Options{TypeAdd: []string{"go:*.go.tmpl"}, Color: "never"})
+	s.out = &out
+	s.ListTypes()
+	assert.Contains(t, out.String(), "\ngo: *.go, *.go.tmpl\ngotest: *_test.go\n")
+}
+
+func TestGlobs(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/a.go":        "foo\n",
+		"src/a_test.go":   "foo\n",
+		"vendor/lib/b.go": "foo\n",
+		"gen/c.go":        "foo\n",
+		"README.MD":       "foo\n",
+		".gitignore":      "gen/\n",
+	})
+	run := func(opts *Options) string { return filesWithFoo(dir, opts) }
+
+	assert.Equal(t, "README.MD\nsrc/a.go\nsrc/a_test.go\n", run(&Options{Globs: []string{"!vendor/**"}}))
+	assert.Equal(t, "src/a.go\nvendor/lib/b.go\n", run(&Options{Globs: []string{"*.go", "!*_test.go"}}))
+
+	// Globs override .gitignore
+	assert.Equal(t, "gen/c.go\n", run(&Options{Globs: []string{"gen/**"}}))
+
+	assert.Equal(t, "", run(&Options{Globs: []string{"*.md"}}))
+	assert.Equal(t, "README.MD\n", run(&Options{IGlobs: []string{"*.md"}}))
+}
+
+func TestLimits(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"top.txt":      "foo\n",
+		"a/mid.txt":    "foo\n",
+		"a/b/deep.txt": "foo\n",
+		"big.txt":      "foo\n" + strings.Repeat("x", 2048),
+	})
+	run := func(opts *Options) string { return filesWithFoo(dir, opts) }
+
+	depth := func(n uint) *uint { return &n }
+	assert.Equal(t, "", run(&Options{MaxDepth: depth(0)}))
+	assert.Equal(t, "big.txt\ntop.txt\n", run(&Options{MaxDepth: depth(1)}))
+	assert.Equal(t, "a/mid.txt\nbig.txt\ntop.txt\n", run(&Options{MaxDepth: depth(2)}))
+	assert.Equal(t, "a/b/deep.txt\na/mid.txt\ntop.txt\n", run(&Options{MaxFilesize: "1K"}))
+	assert.Equal(t, "a/b/deep.txt\na/mid.txt\nbig.txt\ntop.txt\n", run(&Options{MaxFilesize: "3k", OneFileSystem: true}))
+
+	for size, bytes := range map[string]int64{"512": 512, "2K": 2048, "10M": 10 << 20, "1g": 1 << 30} {
+		n, err := parseSize(size)
+		assert.NoError(t, err)
+		assert.Equal(t, bytes, n)
+	}
+	_, err := parseSize("10MB")
+	assert.Error(t, err)
+}
+
+func TestFollow(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/a.txt": "foo\n"})
+	shared := writeFiles(t, map[string]string{"b.txt": "foo\n"})
+	symlink(t, shared, filepath.Join(dir, "shared"))
+	symlink(t, filepath.Join(shared, "b.txt"), filepath.Join(dir, "link.txt"))
+	symlink(t, dir, filepath.Join(dir, "src", "loop"))
+	symlink(t, filepath.Join(dir, "missing"), filepath.Join(dir, "broken"))
+	run := func(opts *Options) string { return filesWithFoo(dir, opts) }
+
+	assert.Equal(t, "src/a.txt\n", run(&Options{}))
+	assert.Equal(t, "link.txt\nshared/b.txt\nsrc/a.txt\n", run(&Options{Follow: true}))
+}
+
+func TestMetadataPredicates(t *testing.T) {
+	dir := writeFiles(t, nil)
+	write := func(name string, size int, perm os.FileMode, age time.Duration) {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, "foo\n"+strings.Repeat("x", size-4))
+		modified := time.Now().Add(-age)
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("new.txt", 100, 0644, time.Hour)
+	write("old.txt", 2000, 0600, 10*24*time.Hour)
+	write("run.sh", 1024, 0755, 3*24*time.Hour)
+	run := func(opts *Options) string { return filesWithFoo(dir, opts) }
+
+	assert.Equal(t, "new.txt\n", run(&Options{Newer: "2d"}))
+	assert.Equal(t, "new.txt\nrun.sh\n", run(&Options{Newer: time.Now().Add(-5 * 24 * time.Hour).Format("2006-01-02")}))
+	assert.Equal(t, "old.txt\nrun.sh\n", run(&Options{Older: "1d"}))
+	assert.Equal(t, "old.txt\n", run(&Options{Size: "+1k"}))
+	assert.Equal(t, "new.txt\n", run(&Options{Size: "-1k"}))
+	assert.Equal(t, "old.txt\n", run(&Options{Size: "2k"}))
+
+	// Sizes are rounded up to the unit, like find
+	assert.Equal(t, "new.txt\nrun.sh\n", run(&Options{Size: "1k"}))
+	assert.Equal(t, "run.sh\n", run(&Options{Perm: "/111"}))
+	assert.Equal(t, "new.txt\nrun.sh\n", run(&Options{Perm: "-644"}))
+	assert.Equal(t, "old.txt\n", run(&Options{Perm: "600"}))
+
+	me, _ := user.Current()
+	assert.Equal(t, "new.txt\nold.txt\nrun.sh\n", run(&Options{Owner: me.Username}))
+	assert.Equal(t, "new.txt\nold.txt\nrun.sh\n", run(&Options{Owner: me.Uid}))
+}
+
+func TestNames(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/user.go":        "package user\n",
+		"src/account.go":     "// user accounts\n",
+		"test/user_test.go":  "",
+		"vendor/user/lib.go": "package user\n",
+		".gitignore":         "vendor/\n",
+	})
+	run := func(opts *Options) string {
+		opts.Locations = []string{dir}
+		opts.NoHeading = true
+		return runSearch(dir, opts)
+	}
+
+	// Empty files are still matched by name
+	assert.Equal(t, "src/user.go\ntest/user_test.go\n", run(&Options{Pattern: "user", Names: true}))
+	assert.Equal(t, "test/user_test.go\n", run(&Options{Pattern: `^test/.*\.go$`, Filename: true}))
+	assert.Equal(t, "src/user.go\n", run(&Options{Pattern: "USER.GO", IgnoreCase: true, Names: true}))
+
+	// Name hits are labeled when contents are searched too
+	assert.Equal(t, "src/account.go:1:// user accounts\nsrc/user.go:name\nsrc/user.go:1:package user\ntest/user_test.go:name\n",
+		run(&Options{Pattern: "user", WithNames: true}))
+}
+
+func TestFindFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/search/ss.go":     "x\n",
+		"src/search/stream.go": "x\n",
+		"src/syntax/lexer.go":  "x\n",
+		"docs/user_service.md": "x\n",
+		"src/UserService.java": "x\n",
+		"vendor/ss/x.go":       "x\n",
+		".gitignore":           "vendor/\n",
+	})
+	run := func(opts *Options) string {
+		opts.Locations = []string{dir}
+		return runSearch(dir, opts)
+	}
+
+	query := func(q string) *string { return &q }
+	assert.Equal(t, "src/search/ss.go\nsrc/search/stream.go\nsrc/syntax/lexer.go\n", run(&Options{FindFile: query("ssgo")}))
+	assert.Equal(t, "src/search/ss.go\n", run(&Options{FindFile: query("ssgo"), MaxResults: 1}))
+
+	// An empty query matches every path, shortest first
+	assert.Equal(t, "src/search/ss.go\nsrc/syntax/lexer.go\n", run(&Options{FindFile: query(""), MaxResults: 2}))
+
+	// Word and camelCase boundaries rank higher than letters inside words
+	assert.Equal(t, "src/UserService.java\ndocs/user_service.md\n", run(&Options{FindFile: query("us")}))
+	assert.Equal(t, "src/UserService.java\n", run(&Options{FindFile: query("US")}))
+
+	// Segment starts are preferred
+	_, positions, ok := fuzzyMatch([]rune("ssgo"), []rune("src/search/ss.go"), true)
+	assert.True(t, ok)
+	assert.Equal(t, []int{4, 11, 14, 15}, positions)
+	_, _, ok = fuzzyMatch([]rune("sx"), []rune("src/search/ss.go"), true)
+	assert.False(t, ok)
+}
+
+func TestFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.go":       "package main\n",
+		"main_test.go":  "package main\n",
+		"empty.go":      "",
+		".hidden/x.go":  "package x\n",
+		"build/out.go":  "package out\n",
+		"docs/guide.md": "# Guide\n",
+		".gitignore":    "build/\n",
+	})
+	run := func(opts *Options) string {
+		opts.Locations = []string{dir}
+		opts.Files = true
+		return runSearch(dir, opts)
+	}
+
+	// Files are listed in the order they're found, and never read
+	assert.Equal(t, "docs/guide.md\nmain.go\nmain_test.go\n", run(&Options{}))
+	assert.Equal(t, "main.go\n", run(&Options{Types: []string{"go"}, TypesNot: []string{"gotest"}}))
+	assert.Equal(t, ".gitignore\n.hidden/x.go\nbuild/out.go\ndocs/guide.md\nmain.go\nmain_test.go\n",
+		run(&Options{Hidden: true, Unrestricted: true}))
+	assert.Equal(t, "main.go\x00main_test.go\x00", run(&Options{Globs: []string{"*.go"}, Null: true}))
+}
+
+func TestTree(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.go":     "fox\n",
+		"api/a.go":    "fox fox\n",
+		"api/b.go":    "dog\n",
+		"api/v1/c.go": "fox\nfox\n",
+	})
+
+	var out bytes.Buffer
+	s := New(&Options{
+		Pattern:      "fox",
+		Locations:    []string{dir},
+		Color:        "never",
+		Unrestricted: true,
+		Tree:         true,
+	})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, dir+` (5)
+├── api (4)
+│   ├── a.go (2)
+│   └── v1 (2)
+│       └── c.go (2)
+└── main.go (1)
+`, out.String())
+
+	// Stdin is the root when it's searched on its own, and is directly under
+	// the root otherwise
+	run := func(locations ...string) string {
+		out.Reset()
+		s := New(&Options{Pattern: "fox", Locations: locations, Color: "never", Tree: true})
+		s.out = &out
+		s.in = strings.NewReader("fox\nfox fox\n")
+		s.Run()
+		return out.String()
+	}
+	assert.Equal(t, "<stdin> (3)\n", run("-"))
+	assert.Equal(t, dir+` (4)
+├── <stdin> (3)
+└── main.go (1)
+`, run(filepath.Join(dir, "main.go"), "-"))
+}
+
+func TestHTMLReport(t *testing.T) {
+	path := filepath.Join(writeFiles(t, nil), "report.html")
+
+	s := New(&Options{
+		Pattern:      "fox",
+		Locations:    []string{testDir},
+		Unrestricted: true,
+		HTML:         path,
+		Context:      1,
+	})
+	s.Run()
+
+	html, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, numFiles1, len(s.report.files))
+	assert.Equal(t, numFiles1*linesPerFile1, strings.Count(string(html), `<mark class="p0">fox</mark>`))
+	assert.Contains(t, string(html), "<tr><td>Files searched</td><td>10</td></tr>")
+
+	// Nothing is printed with --html anyway, so --quiet doesn't matter
+	var out bytes.Buffer
+	s = New(&Options{Pattern: "fox", Locations: []string{testDir}, Unrestricted: true, HTML: path, Quiet: true, Tree: true})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, numFiles1, len(s.report.files))
+	assert.Equal(t, linesPerFile1, len(s.report.files[0].Lines))
+
+	// Every line of a stream is reported with --passthru
+	out.Reset()
+	s = New(&Options{Pattern: "ERROR", Locations: []string{"-"}, HTML: path, Passthru: true})
+	s.out = &out
+	s.in = strings.NewReader("ok\nERROR x\n")
+	s.Run()
+	assert.Equal(t, "", out.String())
+	assert.Equal(t, 1, len(s.report.files))
+	assert.Equal(t, 2, len(s.report.files[0].Lines))
+}
+
+func TestExtract(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"app.conf": "# config for the app server\nhost=\"a,b\" port=80\nhost=c\xff port=x\n"})
+	path := filepath.Join(dir, "app.conf")
+
+	var out bytes.Buffer
+	s := New(&Options{
+		Pattern:   `host=(?P<host>\S+) port=(\d+)?`,
+		Locations: []string{path},
+		Extract:   "csv",
+	})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, "path,line,host,2\n"+
+		path+`,2,"""a,b""",80`+"\n"+
+		path+`,3,c\xff,`+"\n", out.String())
+
+	out.Reset()
+	s = New(&Options{
+		Pattern:   "port",
+		Locations: []string{path},
+		Extract:   "tsv",
+	})
+	s.out = &out
+	s.Run()
+	assert.Equal(t, "path\tline\tmatch\n"+path+"\t2\tport\n"+path+"\t3\tport\n", out.String())
+}
+
+func TestReplacePreview(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"user.js": "getUser(id) + getUser(x)\nnothing\n"})
+	run := func(opts *Options) string {
+		opts.Locations = []string{dir}
+		opts.NoHeading = true
+		return strings.Replace(runSearch(dir, opts), "user.js:", "", -1)
+	}
+
+	replacement := "${1}Account($2)"
+	assert.Equal(t, "1:UserAccount(id) + UserAccount(x)\n",
+		run(&Options{Pattern: `get(User)\((\w+)\)`, Replace: &replacement}))
+
+	replacement = "$accountId"
+	assert.Equal(t, "1:get$accountId(id) + get$accountId(x)\n",
+		run(&Options{Pattern: "User", Replace: &replacement}))
+
+	replacement = "fetch"
+	assert.Equal(t, "1:fetch\n1:fetch\n",
+		run(&Options{Pattern: "get", Replace: &replacement, OnlyMatching: true}))
+}
+
+func TestPreserveCase(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"user.go": "userId UserId USERID userid Userid user_id\n"})
+	run := func(opts *Options) string {
+		opts.IgnoreCase = true
+		opts.PreserveCase = true
+		opts.OnlyMatching = true
+		opts.Locations = []string{dir}
+		opts.NoHeading = true
+		return strings.Replace(runSearch(dir, opts), "user.go:", "", -1)
+	}
+
+	replacement := "accountId"
+	assert.Equal(t, "1:accountId\n1:AccountId\n1:ACCOUNTID\n1:accountid\n1:Accountid\n",
+		run(&Options{Pattern: "userid", Replace: &replacement}))
+
+	// Literal patterns still ignore $ in the template when matching any case
+	replacement = "$1x"
+	assert.Equal(t, "1:$1x\n", run(&Options{Pattern: "USER_ID", Replace: &replacement}))
+}
+
+func TestRewrite(t *testing.T) {
+	// Keep the undo journal out of the home directory
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	before := "package user\n\nfunc getUser(id int) {}\n\nvar u = getUser(1)\n"
+	dir := writeFiles(t, map[string]string{"user.go": before})
+	path := filepath.Join(dir, "user.go")
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(opts *Options) string {
+		var out bytes.Buffer
+		replacement := "fetch$1"
+		opts.Pattern = `get(User)`
+		opts.Replace = &replacement
+		opts.Locations = []string{path}
+		opts.Color = "never"
+		s := New(opts)
+		s.out = &out
+		s.workDir = dir
+		s.Run()
+		return out.String()
+	}
+
+	assert.Equal(t, `--- a/user.go
++++ b/user.go
+@@ -1,5 +1,5 @@
+ package user
+ 
+-func getUser(id int) {}
++func fetchUser(id int) {}
+ 
+-var u = getUser(1)
++var u = fetchUser(1)
+`, run(&Options{Diff: true}))
+
+	// Paths outside the working directory can't be given a prefix to strip
+	assert.Equal(t, "a/src/user.go", diffName("a", "src/user.go"))
+	assert.Equal(t, "/tmp/user.go", diffName("b", "/tmp/user.go"))
+
+	contents, _ := ioutil.ReadFile(path)
+	assert.Equal(t, before, string(contents))
+
+	assert.Equal(t, "user.go: 2 replacements\nReplaced 2 matches in 1 file\n", run(&Options{Write: true}))
+
+	contents, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "package user\n\nfunc fetchUser(id int) {}\n\nvar u = fetchUser(1)\n", string(contents))
+	fi, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// Nothing is left to replace
+	assert.Equal(t, "Replaced 0 matches in 0 files\n", run(&Options{Write: true}))
+
+	// With --follow, links stay links and their target is rewritten once,
+	// as is the target when the replacement is undone
+	target := filepath.Join(writeFiles(t, map[string]string{"user.go": "getUser\n"}), "user.go")
+	links := writeFiles(t, nil)
+	symlink(t, target, filepath.Join(links, "a.go"))
+	symlink(t, target, filepath.Join(links, "b.go"))
+	isLink := func(name string) bool {
+		fi, err := os.Lstat(filepath.Join(links, name))
+		return err == nil && fi.Mode()&os.ModeSymlink != 0
+	}
+
+	replacement := "fetch$1"
+	assert.Equal(t, "a.go: 1 replacement\nReplaced 1 match in 1 file\n", runSearch(links, &Options{
+		Pattern: `get(User)`, Replace: &replacement, Write: true, Follow: true, Locations: []string{links},
+	}))
+	assert.True(t, isLink("a.go") && isLink("b.go"))
+	contents, _ = ioutil.ReadFile(target)
+	assert.Equal(t, "fetchUser\n", string(contents))
+
+	s := New(&Options{Color: "never"})
+	s.out = ioutil.Discard
+	assert.NoError(t, s.Undo())
+	assert.True(t, isLink("a.go"))
+	contents, _ = ioutil.ReadFile(target)
+	assert.Equal(t, "getUser\n", string(contents))
+}
+
+func TestInteractive(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := writeFiles(t, map[string]string{"names.txt": "one foo\ntwo foo\nthree foo\nfour foo\n"})
+	path := filepath.Join(dir, "names.txt")
+
+	var out bytes.Buffer
+	replacement := "bar"
+	s := New(&Options{Pattern: "foo", Replace: &replacement, Interactive: true, Locations: []string{path}, Color: "never"})
+	s.out = &out
+	s.in = strings.NewReader("y\n?\nn\ne\nbaz\nq\n")
+	s.Run()
+
+	contents, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "one bar\ntwo foo\nthree baz\nfour foo\n", string(contents))
+
+	output := strings.Replace(out.String(), path, "names.txt", -1)
+	assert.True(t, strings.HasPrefix(output, `names.txt:1
+-one foo
++one bar
+ two foo
+ three foo
+Replace this match (1/4) [y,n,a,q,e,?]? names.txt:2
+ one foo
+-two foo
++two bar
+ three foo
+ four foo
+Replace this match (2/4) [y,n,a,q,e,?]? y - replace this match
+`), output)
+	assert.True(t, strings.HasSuffix(output, "names.txt: 2 replacements\nReplaced 2 matches in 1 file\n"), output)
+}
+
+func TestUndo(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	dir := writeFiles(t, nil)
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+
+	replace := func() {
+		writeFile(t, a, "foo\nbar\nfoo")
+		writeFile(t, b, "x\nfoo foo\n")
+		replacement := "baz"
+		s := New(&Options{Pattern: "foo", Replace: &replacement, Write: true, Locations: []string{dir}, Color: "never"})
+		s.out = ioutil.Discard
+		s.Run()
+	}
+	undo := func() (string, error) {
+		var out bytes.Buffer
+		s := New(&Options{Color: "never"})
+		s.out = &out
+		err := s.Undo()
+		return strings.Replace(out.String(), dir+"/", "", -1), err
+	}
+
+	replace()
+	contents, _ := ioutil.ReadFile(b)
+	assert.Equal(t, "x\nbaz baz\n", string(contents))
+
+	out, err := undo()
+	assert.NoError(t, err)
+	assert.Equal(t, "Reverted a.txt\nReverted b.txt\nReverted 2 files\n", out)
+	contents, _ = ioutil.ReadFile(a)
+	assert.Equal(t, "foo\nbar\nfoo", string(contents))
+	contents, _ = ioutil.ReadFile(b)
+	assert.Equal(t, "x\nfoo foo\n", string(contents))
+
+	_, err = undo()
+	assert.EqualError(t, err, "nothing to undo")
+
+	// Files changed since the replacement aren't reverted
+	replace()
+	writeFile(t, b, "x\nbaz baz\nmore\n")
+	_, err = undo()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "b.txt")
+	contents, _ = ioutil.ReadFile(a)
+	assert.Equal(t, "baz\nbar\nbaz", string(contents))
+
+	// Files that change during the search aren't rewritten, and aren't in
+	// the journal to stop the others from being undone
+	writeFile(t, a, "foo\n")
+	writeFile(t, b, "foo\n")
+	replacement := "baz"
+	s := New(&Options{Pattern: "foo", Replace: &replacement, Interactive: true, Locations: []string{dir}, Color: "never"})
+	s.out = ioutil.Discard
+	s.in = &beforeRead{strings.NewReader("a\n"), func() {
+		writeFile(t, b, "foo\nchanged\n")
+	}}
+	s.Run()
+	contents, _ = ioutil.ReadFile(a)
+	assert.Equal(t, "baz\n", string(contents))
+
+	out, err = undo()
+	assert.NoError(t, err)
+	assert.Equal(t, "Reverted a.txt\nReverted 1 file\n", out)
+	contents, _ = ioutil.ReadFile(b)
+	assert.Equal(t, "foo\nchanged\n", string(contents))
+
+	// Large files with a change on every line are journaled and reverted
+	// without diffing them
+	large := strings.Repeat("foo bar\n", 100000)
+	writeFile(t, a, large)
+	s = New(&Options{Pattern: "foo", Replace: &replacement, Write: true, Locations: []string{a}, Color: "never"})
+	s.out = ioutil.Discard
+	s.Run()
+	contents, _ = ioutil.ReadFile(a)
+	assert.Equal(t, strings.Repeat("baz bar\n", 100000), string(contents))
+
+	out, err = undo()
+	assert.NoError(t, err)
+	assert.Equal(t, "Reverted a.txt\nReverted 1 file\n", out)
+	contents, _ = ioutil.ReadFile(a)
+	assert.Equal(t, large, string(contents))
+}
+
+// beforeRead calls fn before the first read from the reader
+type beforeRead struct {
+	io.Reader
+	fn func()
+}
+
+func (r *beforeRead) Read(p []byte) (int, error) {
+	if r.fn != nil {
+		r.fn()
+		r.fn = nil
+	}
+	return r.Reader.Read(p)
+}
+
 func BenchmarkSearchDynamicConcurrency(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		s := New(&Options{
-			Pattern:  "fox",
-			Location: testDir,
-			Quiet:    true,
+			Pattern:   "fox",
+			Locations: []string{testDir},
+			Quiet:     true,
 		})
 		s.Run()
 	}
@@ -85,9 +984,9 @@ func BenchmarkSearchDynamicConcurrency(b *testing.B) {
 func BenchmarkSearchDynamicConcurrencyLarge(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		s := New(&Options{
-			Pattern:  "fox",
-			Location: testDir2,
-			Quiet:    true,
+			Pattern:   "fox",
+			Locations: []string{testDir2},
+			Quiet:     true,
 		})
 		s.Run()
 	}
@@ -97,7 +996,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		s := New(&Options{
 			Pattern:   "fox",
-			Location:  testDir,
+			Locations: []string{testDir},
 			Quiet:     true,
 			ShowStats: false,
 		})
@@ -109,7 +1008,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:   "fox",
-// 			Location:  testDir,
+// 			Locations: []string{testDir},
 // 			Quiet:     true,
 // 			ShowStats: false,
 // 			bufSize:   0,
@@ -122,7 +1021,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:   "fox",
-// 			Location:  testDir,
+// 			Locations: []string{testDir},
 // 			Quiet:     true,
 // 			ShowStats: false,
 // 			bufSize:   1,
@@ -135,7 +1034,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:   "fox",
-// 			Location:  testDir,
+// 			Locations: []string{testDir},
 // 			Quiet:     true,
 // 			ShowStats: false,
 // 			bufSize:   2,
@@ -148,7 +1047,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:   "fox",
-// 			Location:  testDir,
+// 			Locations: []string{testDir},
 // 			Quiet:     true,
 // 			ShowStats: false,
 // 			bufSize:   4,
@@ -162,7 +1061,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 2,
 // 		})
@@ -175,7 +1074,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 4,
 // 		})
@@ -188,7 +1087,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 8,
 // 		})
@@ -201,7 +1100,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 16,
 // 		})
@@ -214,7 +1113,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 32,
 // 		})
@@ -227,7 +1126,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir,
+// 			Locations:   []string{testDir},
 // 			Quiet:       true,
 // 			Concurrency: 64,
 // 		})
@@ -241,7 +1140,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 1,
 // 		})
@@ -254,7 +1153,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 2,
 // 		})
@@ -267,7 +1166,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 4,
 // 		})
@@ -280,7 +1179,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 8,
 // 		})
@@ -293,7 +1192,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 16,
 // 		})
@@ -306,7 +1205,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 32,
 // 		})
@@ -319,7 +1218,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:     "fox",
-// 			Location:    testDir2,
+// 			Locations:   []string{testDir2},
 // 			Quiet:       true,
 // 			Concurrency: 64,
 // 		})
@@ -334,7 +1233,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:    "fox",
-// 			Location:   testDir2,
+// 			Locations:  []string{testDir2},
 // 			Quiet:      true,
 // 			MaxWorkers: 4,
 // 		})
@@ -346,7 +1245,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:    "fox",
-// 			Location:   testDir2,
+// 			Locations:  []string{testDir2},
 // 			Quiet:      true,
 // 			MaxWorkers: 8,
 // 		})
@@ -358,7 +1257,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:    "fox",
-// 			Location:   testDir2,
+// 			Locations:  []string{testDir2},
 // 			Quiet:      true,
 // 			MaxWorkers: 9,
 // 		})
@@ -370,7 +1269,7 @@ func BenchmarkSearchStatsOff(b *testing.B) {
 // 	for i := 0; i < b.N; i++ {
 // 		s := New(&Options{
 // 			Pattern:    "fox",
-// 			Location:   testDir2,
+// 			Locations:  []string{testDir2},
 // 			Quiet:      true,
 // 			MaxWorkers: 16,
 // 		})
@@ -2,12 +2,11 @@ package search
 
 import (
 	"bytes"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -27,19 +26,46 @@ var (
 	maxConcurrency = runtime.NumCPU()
 	separator      = string(filepath.Separator)
 
-	highlightMatch  = color.New(color.BgYellow).Add(color.FgBlack).Add(color.Bold)
-	highlightFile   = color.New(color.FgCyan).Add(color.Bold)
-	highlightNumber = color.New(color.FgGreen).Add(color.Bold)
-
 	utf8BOMMarker = []byte{0xEF, 0xBB, 0xBF}
 	pdfMarker     = []byte{'%', 'P', 'D', 'F', '-'}
 	regexChars    = "[{(*+.?^|\\"
 )
 
 type Options struct {
-	Usage    string
-	Pattern  string
-	Location string
+	Usage     string
+	Pattern   string
+	Locations []string
+
+	Types    []string `short:"t" long:"type" value-name:"TYPE" description:"Only search files of TYPE in directories, e.g. go or js; can be repeated (see --type-list)"`
+	TypesNot []string `short:"T" long:"type-not" value-name:"TYPE" description:"Don't search files of TYPE in directories; can be repeated"`
+	TypeAdd  []string `long:"type-add" value-name:"NAME:GLOB[,GLOB...]" description:"Add file name globs to a type, creating it if needed, e.g. 'proto:*.proto'"`
+	TypeList bool     `long:"type-list" description:"Show the file types and their globs, then exit"`
+
+	Globs  []string `short:"g" long:"glob" value-name:"GLOB" description:"Only search files in directories matching GLOB, or exclude them with !GLOB, e.g. '*.go' or '!vendor/**'. Globs have gitignore syntax and override ignore files; later ones take priority"`
+	IGlobs []string `long:"iglob" value-name:"GLOB" description:"Like --glob, but ignoring case"`
+
+	MaxDepth      *uint  `long:"max-depth" value-name:"NUM" description:"Descend at most NUM directories below each path; 1 only searches the files directly inside"`
+	MaxFilesize   string `long:"max-filesize" value-name:"NUM[K|M|G]" description:"Skip files larger than NUM bytes, or kilobytes, megabytes or gigabytes with a suffix"`
+	OneFileSystem bool   `long:"one-file-system" description:"Don't descend into directories on other file systems, such as mounted network shares"`
+
+	Newer string `long:"newer" value-name:"AGE|DATE" description:"Only search files in directories modified within AGE, e.g. 30m, 2d or 1w, or since DATE, e.g. 2026-01-01"`
+	Older string `long:"older" value-name:"AGE|DATE" description:"Only search files in directories last modified more than AGE ago, or before DATE"`
+	Size  string `long:"size" value-name:"[+|-]NUM[K|M|G]" description:"Only search files in directories larger (+) or smaller (-) than a size, or of about that size"`
+	Owner string `long:"owner" value-name:"USER" description:"Only search files in directories owned by USER, a name or ID"`
+	Perm  string `long:"perm" value-name:"[-|/]MODE" description:"Only search files in directories with exactly the octal permissions MODE, all of them (-MODE) or any of them (/MODE)"`
+
+	Names     bool `long:"names" description:"Match the pattern against the paths of files (relative to the PATH they're found in) instead of their contents, printing the paths that match"`
+	Filename  bool `long:"filename" hidden:"yes" description:"Same as --names"`
+	WithNames bool `long:"with-names" description:"Match the pattern against the paths of files as well as their contents. Paths that match are labeled with :name"`
+
+	Files bool `long:"files" description:"Instead of searching, print the paths of the files that would be searched. No PATTERN is given"`
+
+	FindFile   *string `long:"find-file" value-name:"QUERY" description:"Instead of searching, print the paths that best match QUERY as a fuzzy subsequence, e.g. 'srchss' for src/search/ss.go. An empty QUERY lists the shortest paths. No PATTERN is given"`
+	MaxResults uint    `long:"max-results" value-name:"NUM" description:"Print at most NUM paths with --find-file (default: 20)"`
+
+	Follow bool `short:"L" long:"follow" description:"Follow symbolic links to files and directories"`
+
+	FilesFrom string `long:"files-from" value-name:"FILE" description:"Also search the files listed in FILE, one per line (use - to read the list from stdin)"`
 
 	IgnoreCase   bool `short:"i" long:"ignore-case" description:"Ignore case sensitivity when matching"`
 	Hidden       bool `long:"hidden" description:"Search hidden files"`
@@ -49,6 +75,43 @@ type Options struct {
 	Debug     bool `short:"D" long:"debug" description:"Show verbose debug information"`
 	ShowStats bool `long:"stats" description:"Show stats (# matches, files searched, time taken, etc.)"`
 
+	Color            string `long:"color" default:"auto" choice:"auto" choice:"always" choice:"never" description:"When to use colors; auto only colors output to a terminal"`
+	NoHeading        bool   `long:"no-heading" description:"Print the file path on every matching line instead of once above its matches"`
+	Null             bool   `short:"0" long:"null" description:"Follow file paths with a NUL byte (for use with xargs -0)"`
+	FilesWithMatches bool   `short:"l" long:"files-with-matches" description:"Only print the paths of files that contain matches"`
+
+	Patterns      []string `short:"e" long:"regexp" value-name:"PATTERN" description:"Search for PATTERN; may be given multiple times, in which case PATH is the first argument"`
+	Colors        []string `long:"colors" value-name:"SPEC" description:"Set an output color, e.g. match:fg:red, path:style:underline or line:none. Types are path, line, column, separator, context, match (match2, match3... for additional patterns), and keyword, string, comment and number for --syntax. GREP_COLORS is also read, as is LS_COLORS to color paths by file name"`
+	Column        bool     `long:"column" description:"Show the column number of the first match on each line"`
+	AfterContext  uint     `short:"A" long:"after-context" value-name:"NUM" description:"Show NUM lines after each match"`
+	BeforeContext uint     `short:"B" long:"before-context" value-name:"NUM" description:"Show NUM lines before each match"`
+	Context       uint     `short:"C" long:"context" value-name:"NUM" description:"Show NUM lines before and after each match"`
+
+	MaxColumns        *uint `short:"M" long:"max-columns" value-name:"NUM" description:"Don't print lines longer than NUM bytes, such as those of minified files; a note with the number of matches is shown instead. 0 prints every line in full (default: 4096)"`
+	MaxColumnsPreview bool  `long:"max-columns-preview" description:"Instead of omitting lines longer than --max-columns, show a preview of the text around their matches"`
+
+	HTML string `long:"html" value-name:"FILE" description:"Write the results to FILE as a standalone HTML report instead of printing them"`
+
+	Tree bool `long:"tree" description:"Show the directory tree of files with matches, with the number of matches in each file and directory"`
+
+	Extract string `long:"extract" optional:"yes" optional-value:"csv" choice:"csv" choice:"tsv" description:"Print a CSV (or TSV) row for each match, with columns for the path, line number and each capture group of the pattern"`
+
+	Replace      *string `short:"r" long:"replace" value-name:"TEMPLATE" description:"Show matches replaced by TEMPLATE, without changing any files. Regex capture groups can be referenced as $1 or ${name}"`
+	OnlyMatching bool    `short:"o" long:"only-matching" description:"Print only the matched (or replaced) parts of each line, one per line"`
+	Write        bool    `long:"write" description:"Apply --replace to the files, then print the number of replacements in each"`
+	Diff         bool    `long:"diff" description:"Print a unified diff of the changes --replace would make, without changing any files"`
+	PreserveCase bool    `long:"preserve-case" description:"Give each replacement the case of the text it replaces: lower, UPPER, Title, camelCase or PascalCase. Use with -i to match every case"`
+	Interactive  bool    `long:"interactive" description:"Like --write, but ask before making each replacement"`
+	Undo         bool    `long:"undo" description:"Instead of searching, revert the files changed by the last --write or --interactive, unless they've been modified since. No PATTERN or PATH is given"`
+
+	Passthru bool `long:"passthru" description:"Print every line, highlighting any matches. Lines are labeled with their file and line number when more than one file is searched"`
+
+	Format string `long:"format" value-name:"TEMPLATE" description:"Print each match using TEMPLATE, e.g. '{path}:{line}:{col}: {match}'. Placeholders are {path}, {relpath}, {line}, {col}, {offset}, {match}, {text} (the whole line), {pattern}, {size} and capture groups by number or name ({1}, {name})"`
+
+	Syntax bool `long:"syntax" description:"Color printed lines according to their language (Go, Python, JavaScript/TypeScript, shell, YAML and JSON are supported)"`
+
+	HyperlinkFormat string `long:"hyperlink-format" value-name:"FORMAT" description:"Make file paths and line numbers clickable links when printing to a terminal. FORMAT is a template using {host}, {path}, {line} and {column}, such as vscode://file{path}:{line}:{column}, or one of default, vscode, cursor, idea, macvim, textmate"`
+
 	bufSize uint
 }
 
@@ -57,30 +120,98 @@ type searchFile struct {
 	path    string
 	buf     []byte
 	size    int64
-	matches []int
+	matches []match
+
+	// Whether this is a stream such as stdin rather than a file on disk
+	stream bool
+}
+
+// match is the byte range of a single match. Offsets are relative to the
+// start of the file (or stream) that was searched.
+type match struct {
+	start int
+	end   int
+
+	// Index of the pattern that matched
+	pattern int
+
+	// Start and end offsets of each regex capture group, beginning with the
+	// whole match. Nil when the pattern has no groups.
+	groups []int
 }
 
 type printFile struct {
 	output string
 	index  uint64
+
+	// Whether this is the last output for the index
+	done bool
 }
 
 type SuperSearch struct {
 	opts *Options
 
-	isRegex      bool
-	searchRegexp *regexp.Regexp
-	stringFinder *stringFinder
+	patterns   []*pattern
+	colors     *theme
+	hyperlinks *hyperlinker
+	format     *formatter
+
+	// Replacement template, or nil when not replacing
+	replace *string
+
+	// Whether replacements are made to files rather than printed, and the
+	// changes to make with --write, or show with --diff
+	rewrite bool
+	edits   []*edit
+	editsMu sync.Mutex
+
+	// Whether the pattern is matched against file paths, and whether only
+	// against them, with --names and --with-names
+	names     bool
+	namesOnly bool
+
+	// Files to rank with --find-file
+	candidates []*candidate
+
+	// Whether more than one file may be searched, in which case every line
+	// printed by --passthru is labeled with its file and line number
+	multipleFiles bool
+
+	// Which files in directories are searched, with --type and --type-not
+	types *typeFilter
+
+	// Files larger than this aren't searched, unless it's 0
+	maxFilesize int64
+
+	// Which files in directories are searched, by their metadata
+	meta *metaFilter
+
+	// Directories already scanned with --follow, by fileKey
+	visited map[interface{}]bool
+
+	// Header of the --extract output
+	extractColumns []string
+
+	// Number of lines to show around each match
+	beforeContext int
+	afterContext  int
+
+	// Lines longer than this are omitted or previewed; 0 if none are
+	maxColumns int
 
 	searchQueue chan *searchFile
 	workerQueue chan *searchFile
-	// printQueue  chan *printFile
+	printQueue  chan *printFile
+	printDone   chan struct{}
 
-	// Map of file indexes to empty structs, which the printLoop goroutine uses
-	// to determine what to print next
-	skipFiles *sync.Map
+	// The index of the last file given to printLoop. Indexes are numbered
+	// across all of the roots in the order files are found, and only the
+	// findFiles goroutine uses this.
+	lastIndex uint64
 
-	// These are used for --stats; some of these aren't tracked by default
+	// These are used for --stats and --html; some of these aren't tracked
+	// by default
+	trackStats    bool
 	numMatches    uint64
 	filesMatched  uint64
 	filesSearched uint64
@@ -89,10 +220,26 @@ type SuperSearch struct {
 
 	workDir string
 	wg      *sync.WaitGroup
+
+	// Results collected for --html
+	report *report
+
+	// Match counts by file, for --tree, and the directory they're relative to
+	tree     *treeNode
+	treeMu   sync.Mutex
+	treeRoot string
+	treeName string
+
+	// All output goes through print, which serializes writes to out
+	out   io.Writer
+	outMu sync.Mutex
+
+	// Stdin, which is searched for - and answers --interactive prompts
+	in io.Reader
 }
 
 func New(opts *Options) *SuperSearch {
-	logger.Debug("Searching %q for %q", opts.Location, opts.Pattern)
+	logger.Debug("Searching %q for %q", opts.Locations, opts.Pattern)
 
 	if opts.Debug {
 		logger.DebugMode = true
@@ -101,17 +248,21 @@ func New(opts *Options) *SuperSearch {
 	if opts.IgnoreCase {
 		logger.Debug("Using case insensitive search %v", opts.Pattern)
 	}
+	if opts.PreserveCase && opts.Replace == nil {
+		logger.Fail("--preserve-case requires --replace")
+	}
 
-	var rgx *regexp.Regexp
-	var sf *stringFinder
-	isRgx := false
-	if isRegex(opts.Pattern) {
-		logger.Debug("Using regex search")
-		rgx = regexp.MustCompile(opts.Pattern)
-		isRgx = true
-	} else {
-		logger.Debug("Using Boyer-Moore string search")
-		sf = makeStringFinder(opts.Pattern)
+	texts := opts.Patterns
+	if opts.Pattern != "" {
+		texts = append([]string{opts.Pattern}, texts...)
+	}
+	var patterns []*pattern
+	for _, text := range texts {
+		p, err := newPattern(text, opts.IgnoreCase)
+		if err != nil {
+			logger.Fail(err.Error())
+		}
+		patterns = append(patterns, p)
 	}
 
 	wd, err := os.Getwd()
@@ -119,35 +270,157 @@ func New(opts *Options) *SuperSearch {
 		logger.Fail(err.Error())
 	}
 
-	return &SuperSearch{
-		searchRegexp: rgx,
-		stringFinder: sf,
-		isRegex:      isRgx,
-		opts:         opts,
-		workDir:      wd,
+	switch opts.Color {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		color.NoColor = !isTerminal(os.Stdout)
+	}
+
+	// --find-file highlights with the color of the first pattern, without
+	// having any patterns
+	numColors := len(patterns)
+	if numColors == 0 {
+		numColors = 1
+	}
+	colors, err := newTheme(opts.Colors, numColors)
+	if err != nil {
+		logger.Fail(err.Error())
+	}
+
+	types, err := newTypeFilter(opts)
+	if err != nil {
+		logger.Fail(err.Error())
+	}
+
+	meta, err := newMetaFilter(opts, time.Now())
+	if err != nil {
+		logger.Fail(err.Error())
+	}
+
+	var maxFilesize int64
+	if opts.MaxFilesize != "" {
+		maxFilesize, err = parseSize(opts.MaxFilesize)
+		if err != nil {
+			logger.Fail("invalid --max-filesize: %v", err)
+		}
+	}
+
+	var format *formatter
+	if opts.Format != "" {
+		format, err = parseFormat(opts.Format, patterns)
+		if err != nil {
+			logger.Fail(err.Error())
+		}
+	}
+
+	// Escape sequences would only garble output that isn't going to a terminal
+	var hyperlinks *hyperlinker
+	if opts.HyperlinkFormat != "" && opts.HyperlinkFormat != "none" {
+		hyperlinks, err = newHyperlinker(opts.HyperlinkFormat)
+		if err != nil {
+			logger.Fail(err.Error())
+		}
+		if !isTerminal(os.Stdout) {
+			hyperlinks = nil
+		}
+	}
+
+	rewrite := opts.Write || opts.Diff || opts.Interactive
+	if rewrite && opts.Replace == nil {
+		logger.Fail("--write, --diff and --interactive require --replace")
+	}
+	if opts.Diff && (opts.Write || opts.Interactive) {
+		logger.Fail("--diff can't be used with --write or --interactive")
+	}
+	readsStdin := false
+	for _, l := range opts.Locations {
+		readsStdin = readsStdin || l == stdinPath
+	}
+	if readsStdin && opts.FilesFrom == stdinPath {
+		logger.Fail("stdin can't be both searched and used for --files-from")
+	}
+	if rewrite && readsStdin {
+		logger.Fail("--write, --diff and --interactive can't be used with stdin")
+	}
+
+	// Paths that match are only ever printed as they are
+	names := opts.Names || opts.Filename || opts.WithNames
+	if names && (opts.Format != "" || opts.Extract != "" || opts.HTML != "" || opts.Tree || rewrite) {
+		logger.Fail("--names and --with-names can't be used with --format, --extract, --html, --tree, --write, --diff or --interactive")
+	}
+
+	before, after := opts.BeforeContext, opts.AfterContext
+	if before == 0 {
+		before = opts.Context
+	}
+	if after == 0 {
+		after = opts.Context
+	}
+
+	maxColumns := defaultMaxColumns
+	if opts.MaxColumns != nil {
+		maxColumns = int(*opts.MaxColumns)
+	}
+
+	ss := &SuperSearch{
+		patterns:      patterns,
+		colors:        colors,
+		hyperlinks:    hyperlinks,
+		format:        format,
+		types:         types,
+		maxFilesize:   maxFilesize,
+		names:         names,
+		namesOnly:     opts.Names || opts.Filename,
+		meta:          meta,
+		replace:       opts.Replace,
+		rewrite:       rewrite,
+		beforeContext: int(before),
+		afterContext:  int(after),
+		maxColumns:    maxColumns,
+		opts:          opts,
+		workDir:       wd,
 
 		searchQueue: make(chan *searchFile),
 		workerQueue: make(chan *searchFile),
-		// printQueue:  make(chan *printFile),
+		printQueue:  make(chan *printFile),
+		printDone:   make(chan struct{}),
 
-		skipFiles: new(sync.Map),
+		wg:  new(sync.WaitGroup),
+		out: os.Stdout,
+		in:  os.Stdin,
+	}
 
-		wg: new(sync.WaitGroup),
+	if opts.HTML != "" {
+		ss.report = &report{}
+	}
+	if opts.Extract != "" {
+		ss.extractColumns = extractColumns(patterns)
 	}
+	ss.trackStats = opts.ShowStats || ss.report != nil
+
+	return ss
 }
 
 // Main program logic
 func (ss *SuperSearch) Run() {
 	var start time.Time
-	if ss.opts.ShowStats {
+	if ss.trackStats {
 		start = time.Now()
 	}
 
+	if ss.opts.Extract != "" && !ss.opts.Quiet {
+		ss.printExtractHeader()
+	}
+
 	// processFiles takes files from findFiles and delegates them to workers
 	// over the searchQueue channel. Workers then search the files and send
 	// results over to printLoop, which concatonates as many of the results
 	// as it can before printing.
 	go ss.processFiles()
+	go ss.printLoop()
 
 	// Synchronously finds files and send them into searchQueue,
 	// which are then processed by the processFiles goroutine
@@ -162,8 +435,33 @@ func (ss *SuperSearch) Run() {
 	logger.Debug("Closing search queue")
 	close(ss.searchQueue)
 
-	if ss.opts.ShowStats {
+	// Everything else is printed after the results of the search
+	close(ss.printQueue)
+	<-ss.printDone
+
+	if ss.opts.Tree {
+		ss.printTree()
+	}
+
+	if ss.opts.FindFile != nil {
+		ss.printFoundFiles()
+	}
+
+	if ss.rewrite {
+		ss.applyEdits()
+	}
+
+	if ss.trackStats {
 		ss.duration = time.Since(start)
+	}
+
+	if ss.report != nil {
+		if err := ss.writeReport(ss.opts.HTML); err != nil {
+			logger.Fail(err.Error())
+		}
+	}
+
+	if ss.opts.ShowStats {
 		ss.printStats()
 	}
 }
@@ -205,80 +503,116 @@ PROCESSLOOP:
 }
 
 // This runs in its own goroutine, receiving output strings and indexes.
-// As the print loop receives output, it is cached until the current index
-// is received. Once that happens, the printer will attempt to concatonate
-// the next n subsequent outputs into one string builder for efficiency
-// while maintaining order.
+// Output for the current index is printed as soon as it's received, so that
+// streams are printed as they're read. Output for later indexes is cached
+// until every index before it is done. Once that happens, the printer will
+// attempt to concatonate the next n subsequent outputs into one string
+// builder for efficiency while maintaining order.
 func (ss *SuperSearch) printLoop() {
 	var (
-		// Mapping of indexes to output strings
-		// TODO make this global syncmap..get around a channel
-		print = make(map[uint64]string)
+		// Output cached for indexes after the current one, and which of
+		// them are done
+		cached = make(map[uint64]*strings.Builder)
+		done   = make(map[uint64]bool)
 
-		// The current print index. The printer will wait until this
-		// is recieved before attemptint to print.
+		// The current print index
 		i uint64 = 1
 
 		output strings.Builder
 	)
 
-	for {
+	for pf := range ss.printQueue {
+		if pf.index != i {
+			b, ok := cached[pf.index]
+			if !ok {
+				b = new(strings.Builder)
+				cached[pf.index] = b
+			}
+			b.WriteString(pf.output)
+			if pf.done {
+				done[pf.index] = true
+			}
+			continue
+		}
+
 		output.Reset()
+		output.WriteString(pf.output)
 
 		// Add as many outputs together as we can before printing
-		for {
-			out, ok := print[i]
-			if ok {
+		for finished := pf.done; finished; {
+			i++
+			if b, ok := cached[i]; ok {
 				logger.DebugGreen("Adding %v to string builder", i)
-				output.WriteString(out)
-				i++
-			}
-			if _, ok := ss.skipFiles.Load(i); ok {
-				logger.DebugGreen("Printer skipping %v", i)
-				i++
-			} else {
-				break
+				output.WriteString(b.String())
+				delete(cached, i)
 			}
+			finished = done[i]
+			delete(done, i)
 		}
 
-		if !ss.opts.Quiet && output.Len() > 0 {
-			fmt.Print(output.String())
+		if output.Len() > 0 {
+			ss.print(output.String())
 		}
 	}
 
-	logger.Debug("Print loop done\n%v", print)
-	fmt.Println(*ss.skipFiles)
-	ss.wg.Done()
+	logger.Debug("Print loop done")
+	close(ss.printDone)
+}
+
+// Returns the index for the next file with output, in the order found
+func (ss *SuperSearch) nextIndex() uint64 {
+	ss.lastIndex++
+	return ss.lastIndex
+}
+
+// Tells printLoop that there's no more output for index
+func (ss *SuperSearch) done(index uint64) {
+	ss.printQueue <- &printFile{index: index, done: true}
 }
 
 func (ss *SuperSearch) findFiles() {
-	fi, err := os.Stat(ss.opts.Location)
-	if err != nil {
-		logger.Fail("invalid location input %v", ss.opts.Location)
-	}
+	roots := ss.resolveRoots()
+	ss.setTreeRoot(roots)
+	ss.multipleFiles = len(roots) > 1 || len(roots) == 1 && roots[0].info != nil && roots[0].info.IsDir()
 
 	usr, err := user.Current()
 	if err != nil {
 		logger.Fail(err.Error())
 	}
 
-	switch mode := fi.Mode(); {
-
-	case mode.IsDir():
-		var m gitignore.Matcher
-		if !ss.opts.Unrestricted {
-			ps, _ := gitignore.ReadIgnoreFile(filepath.Join(usr.HomeDir, ".gitignore_global"))
-			m = gitignore.NewMatcher(ps)
+	for _, r := range roots {
+		if r.path == stdinPath {
+			if !ss.namesOnly && ss.opts.FindFile == nil && !ss.opts.Files {
+				ss.searchStream(ss.in, stdinLabel)
+			}
+			continue
 		}
-		ss.scanDir(ss.opts.Location, m)
 
-	case mode.IsRegular():
-		ss.queue(ss.opts.Location, fi.Size())
+		switch mode := r.info.Mode(); {
+
+		case mode.IsDir():
+			if !ss.visit(r.path, r.info) {
+				continue
+			}
+			var m gitignore.Matcher
+			if !ss.opts.Unrestricted {
+				ps, _ := gitignore.ReadIgnoreFile(filepath.Join(usr.HomeDir, ".gitignore_global"))
+				m = gitignore.NewMatcher(ps)
+			}
+			ss.scanDir(r.path, m, ss.newScanRoot(r), 1)
+
+		case mode.IsRegular():
+			ss.found(ss.newScanRoot(r), r.path, r.info.Size())
+		}
 	}
 }
 
-// Recursively go through directory, sending all files into searchQueue
-func (ss *SuperSearch) scanDir(dir string, m gitignore.Matcher) {
+// Queues the files in dir, which is depth levels below the root being searched
+func (ss *SuperSearch) scanDir(dir string, m gitignore.Matcher, root *scanRoot, depth int) {
+	if ss.opts.MaxDepth != nil && depth > int(*ss.opts.MaxDepth) {
+		logger.Debug("Skipping directory below --max-depth %v", dir)
+		return
+	}
 	logger.Debug("Scanning directory %v", dir)
 
 	dirInfo, err := ioutil.ReadDir(dir)
@@ -298,19 +632,57 @@ func (ss *SuperSearch) scanDir(dir string, m gitignore.Matcher) {
 			logger.Debug("Skipping hidden file %v", fi.Name())
 			continue
 		}
-		if !ss.opts.Unrestricted && strings.Contains(fi.Name(), ".min") {
-			logger.Debug("Skipping minified file")
+		path := filepath.Join(dir, fi.Name())
+		parts := strings.Split(path, separator)[1:]
+
+		// Links are matched against ignore files and globs by their own
+		// names, but are otherwise treated as their targets
+		var linkErr error
+		if ss.opts.Follow && fi.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(path); err != nil {
+				linkErr = err
+			} else {
+				fi = target
+			}
+		}
+
+		// Globs given on the command line take priority over ignore files
+		switch root.globs.match(parts, fi.IsDir()) {
+		case gitignore.Exclude:
+			logger.Debug("Skipping glob match: %v", path)
 			continue
+		case gitignore.NoMatch:
+			if !ss.opts.Unrestricted && m.Match(parts, fi.IsDir()) {
+				logger.Debug("Skipping gitignore match: %v", path)
+				continue
+			}
 		}
-		path := filepath.Join(dir, fi.Name())
-		if !ss.opts.Unrestricted && m.Match(strings.Split(path, separator)[1:], fi.IsDir()) {
-			logger.Debug("Skipping gitignore match: %v", path)
+
+		if linkErr != nil {
+			logger.Warn("Skipping broken link %v: %v", path, linkErr)
 			continue
 		}
+
 		if fi.IsDir() {
-			ss.scanDir(path, m)
+			if !root.contains(fi) {
+				logger.Debug("Skipping directory on another file system: %v", path)
+				continue
+			}
+			if !ss.visit(path, fi) {
+				logger.Debug("Skipping directory already searched: %v", path)
+				continue
+			}
+			ss.scanDir(path, m, root, depth+1)
 		} else if fi.Mode().IsRegular() {
-			ss.queue(path, fi.Size())
+			if !ss.types.match(fi.Name()) {
+				logger.Debug("Skipping file of another type: %v", path)
+				continue
+			}
+			if !ss.meta.match(fi) {
+				logger.Debug("Skipping file filtered by its metadata: %v", path)
+				continue
+			}
+			ss.found(root, path, fi.Size())
 		}
 	}
 
@@ -323,11 +695,22 @@ func (ss *SuperSearch) queue(path string, size int64) {
 		logger.Debug("Skipping empty file %v", path)
 		return
 	}
+	if ss.maxFilesize > 0 && size > ss.maxFilesize {
+		logger.Debug("Skipping file larger than --max-filesize %v", path)
+		return
+	}
+
+	// The file would be searched, which is all --files needs to know
+	if ss.opts.Files {
+		ss.listFile(path)
+		return
+	}
+
 	logger.Debug("Queuing %v", path)
 	ss.wg.Add(1)
 	ss.searchQueue <- &searchFile{
 		path:  path,
-		index: ss.filesSearched,
+		index: ss.nextIndex(),
 		size:  size,
 	}
 }
@@ -354,10 +737,6 @@ func (ss *SuperSearch) newWorker() {
 
 			logger.Debug("Worker %v searching %v", workerNum, sf.path)
 			ss.searchFile(sf)
-
-			// if !matchFound {
-			// 	ss.skipFiles.Store(sf.index, struct{}{})
-			// }
 		}
 
 		logger.Debug("Worker %v finished", workerNum)
@@ -366,6 +745,7 @@ func (ss *SuperSearch) newWorker() {
 
 func (ss *SuperSearch) searchFile(sf *searchFile) bool {
 	defer ss.wg.Done()
+	defer ss.done(sf.index)
 
 	file, err := os.Open(sf.path)
 	if err != nil {
@@ -385,84 +765,49 @@ func (ss *SuperSearch) searchFile(sf *searchFile) bool {
 		return false
 	}
 
-	if ss.isRegex {
-		return ss.searchFileRegex(sf)
-	} else {
-		return ss.searchFileBoyerMoore(sf)
+	sf.matches = findAll(ss.patterns, sf.buf)
+	if len(sf.matches) == 0 {
+		// Every line is printed with --passthru, matches or not
+		if ss.opts.Passthru && !ss.rewrite {
+			ss.handleMatches(sf)
+		}
+		return false
 	}
-}
-
-func (ss *SuperSearch) searchFileRegex(sf *searchFile) bool {
 
-	var output strings.Builder
-	matchFound := false
-	lastIndex := 0
-	lineNo := 1
-
-	for i := 0; i < len(sf.buf); i++ {
-		if sf.buf[i] == '\n' {
-			var line = sf.buf[lastIndex:i]
-			ixs := ss.searchRegexp.FindAllIndex(line, -1)
-
-			// Skip binary files
-			if !matchFound && !utf8.Valid(line) {
-				return false
-			}
-
-			// We found matches
-			if ixs != nil {
-
-				if ss.opts.ShowStats {
-					atomic.AddUint64(&ss.numMatches, uint64(len(ixs)))
-				}
-
-				if !matchFound {
-					matchFound = true
-					if ss.opts.ShowStats {
-						atomic.AddUint64(&ss.filesMatched, 1)
-					}
-					// Print the file name if we find a match
-					output.WriteString(highlightFile.Sprintf("%v\n", sf.path))
-				}
-
-				// Print line number, followed by each match
-				output.WriteString(highlightNumber.Sprintf("%v:", lineNo))
-				lastIndex = 0
-
-				// Loop through match indexes, output highlighted match
-				for _, i := range ixs {
-					output.Write(line[lastIndex:i[0]])
-					output.WriteString(highlightMatch.Sprint(string(line[i[0]:i[1]])))
-					lastIndex = i[1]
-				}
-				output.Write(line[lastIndex:])
-				output.WriteRune('\n')
-			}
-
-			lastIndex = i + 1
-			lineNo++
-		}
+	if ss.trackStats {
+		atomic.AddUint64(&ss.filesMatched, 1)
 	}
 
-	if matchFound {
-		output.WriteRune('\n')
-		fmt.Print(output.String())
+	if ss.rewrite {
+		fi, err := file.Stat()
+		if err != nil {
+			return false
+		}
+		atomic.AddUint64(&ss.numMatches, uint64(len(sf.matches)))
+		ss.recordEdit(sf, fi)
 		return true
 	}
 
-	return false
+	ss.handleMatches(sf)
+	return true
 }
 
-func (ss *SuperSearch) searchFileBoyerMoore(sf *searchFile) bool {
-	sf.matches = ss.stringFinder.findAll(sf.buf)
-	if len(sf.matches) == 0 {
-		return false
-	}
-	if ss.opts.ShowStats {
-		atomic.AddUint64(&ss.filesMatched, 1)
+// forEachLine calls fn with the number, starting offset and contents
+// (excluding the newline) of each line in buf, until fn returns false
+func forEachLine(buf []byte, fn func(lineNo, offset int, line []byte) bool) {
+	lineNo := 1
+	for offset := 0; offset < len(buf); lineNo++ {
+		end := bytes.IndexByte(buf[offset:], '\n')
+		if end < 0 {
+			end = len(buf)
+		} else {
+			end += offset
+		}
+		if !fn(lineNo, offset, buf[offset:end]) {
+			return
+		}
+		offset = end + 1
 	}
-	ss.handleMatches(sf)
-	return true
 }
 
 func isBinary(buf []byte) bool {
@@ -490,6 +835,6 @@ func isRegex(pattern string) bool {
 
 func (ss *SuperSearch) printStats() {
 	p := message.NewPrinter(language.English)
-	p.Printf("%v matches\n%v files contained matches\n%v files searched\n%v seconds",
+	p.Fprintf(ss.out, "%v matches\n%v files contained matches\n%v files searched\n%v seconds\n",
 		ss.numMatches, ss.filesMatched, ss.filesSearched, ss.duration.Seconds())
 }
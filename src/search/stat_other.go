@@ -0,0 +1,22 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package search
+
+import "os"
+
+// Device IDs aren't available here, so --one-file-system has no effect
+func deviceID(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// Returns a key identifying the file, whatever path it's reached by. Without
+// inode numbers, this is the path with all symlinks resolved.
+func fileKey(path string, fi os.FileInfo) (interface{}, error) {
+	return resolvedPath(path)
+}
+
+// Files don't have numeric owners here, so --owner matches nothing
+func fileOwner(fi os.FileInfo) (uint32, bool) {
+	return 0, false
+}
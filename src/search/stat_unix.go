@@ -0,0 +1,36 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package search
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the ID of the device holding the file, for --one-file-system
+func deviceID(fi os.FileInfo) (uint64, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// Returns a key identifying the file, whatever path it's reached by
+func fileKey(path string, fi os.FileInfo) (interface{}, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return resolvedPath(path)
+	}
+	return [2]uint64{uint64(st.Dev), uint64(st.Ino)}, nil
+}
+
+// Returns the user ID of the file's owner, for --owner
+func fileOwner(fi os.FileInfo) (uint32, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return st.Uid, true
+}
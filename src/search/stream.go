@@ -0,0 +1,130 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/wellsjo/SuperSearch/src/logger"
+)
+
+const (
+	// Searching this path reads from stdin
+	stdinPath = "-"
+
+	// The name printed for matches found in stdin
+	stdinLabel = "<stdin>"
+)
+
+// PipedStdin reports whether stdin is a pipe or a redirected file, which is
+// searched when no path is given. Terminals and devices such as /dev/null,
+// which is stdin for many scripts and cron jobs, aren't.
+func PipedStdin() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeNamedPipe != 0 || fi.Mode().IsRegular()
+}
+
+// searchStream searches r line by line, printing the results for each line as
+// soon as it's read. This keeps memory use constant and output timely for
+// unbounded input, such as `tail -f app.log | ss --passthru ERROR -`.
+func (ss *SuperSearch) searchStream(r io.Reader, label string) {
+	sf := &searchFile{
+		index:  ss.nextIndex(),
+		path:   label,
+		size:   -1,
+		stream: true,
+	}
+	defer ss.done(sf.index)
+
+	var (
+		p       = ss.newPrinter(sf)
+		br      = bufio.NewReader(r)
+		lineNo  = 1
+		offset  = 0
+		matched = false
+	)
+
+	atomic.AddUint64(&ss.filesSearched, 1)
+
+	if ss.report != nil {
+		p.report = &reportFile{Path: label}
+		defer func() {
+			if p.report.Matches > 0 {
+				ss.report.add(p.report)
+			}
+		}()
+	}
+
+	for {
+		// ReadBytes returns a new slice for each line, so lines held for
+		// before-context aren't overwritten
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			text := bytes.TrimSuffix(line, []byte{'\n'})
+			ms := shiftMatches(findAll(ss.patterns, text), offset)
+
+			if len(ms) > 0 {
+				atomic.AddUint64(&ss.numMatches, uint64(len(ms)))
+				if p.report != nil {
+					p.report.Matches += len(ms)
+				}
+				if !matched {
+					matched = true
+					if ss.trackStats {
+						atomic.AddUint64(&ss.filesMatched, 1)
+					}
+				}
+			}
+
+			if ss.opts.Tree && len(ms) > 0 {
+				ss.recordTree(sf, len(ms))
+			}
+
+			switch {
+			case p.report != nil:
+				p.emit(lineNo, offset, text, ms)
+			case ss.opts.Quiet, ss.opts.Tree:
+			case ss.opts.FilesWithMatches:
+				if matched {
+					p.fileName()
+					p.flush()
+					return
+				}
+			default:
+				p.emit(lineNo, offset, text, ms)
+			}
+			p.flush()
+
+			lineNo++
+			offset += len(line)
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				logger.Debug("Failed reading %v: %v", label, err)
+			}
+			break
+		}
+	}
+
+	p.finish()
+}
+
+// Moves line-relative matches to be relative to the start of the stream
+func shiftMatches(ms []match, offset int) []match {
+	for i := range ms {
+		ms[i].start += offset
+		ms[i].end += offset
+		for j := range ms[i].groups {
+			if ms[i].groups[j] >= 0 {
+				ms[i].groups[j] += offset
+			}
+		}
+	}
+	return ms
+}
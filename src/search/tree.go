@@ -0,0 +1,97 @@
+package search
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// treeNode is a file or directory in the --tree summary
+type treeNode struct {
+	name     string
+	matches  uint64
+	children map[string]*treeNode
+}
+
+// Records the number of matches in a file for --tree, adding them to each of
+// its parent directories
+func (ss *SuperSearch) recordTree(sf *searchFile, matches int) {
+	ss.treeMu.Lock()
+	defer ss.treeMu.Unlock()
+
+	if ss.tree == nil {
+		ss.tree = &treeNode{name: ss.treeName}
+	}
+
+	node := ss.tree
+	node.matches += uint64(matches)
+
+	// Streams aren't in any directory. On their own they're the root, and
+	// otherwise they're directly under it.
+	var names []string
+	if sf.stream {
+		if ss.treeRoot == stdinPath {
+			return
+		}
+		names = []string{sf.path}
+	} else {
+		// Searching a single file gives a tree of just the root
+		rel, err := filepath.Rel(ss.treeRoot, sf.path)
+		if err != nil || rel == "." {
+			return
+		}
+		names = strings.Split(rel, separator)
+	}
+
+	for _, name := range names {
+		child, ok := node.children[name]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[string]*treeNode)
+			}
+			child = &treeNode{name: name}
+			node.children[name] = child
+		}
+		child.matches += uint64(matches)
+		node = child
+	}
+}
+
+// Prints the directory hierarchy of the files with matches, annotated with the
+// number of matches in each file and directory
+func (ss *SuperSearch) printTree() {
+	if ss.tree == nil {
+		return
+	}
+
+	var out strings.Builder
+	ss.writeTreeNode(&out, ss.tree, "", "")
+	ss.print(out.String())
+}
+
+func (ss *SuperSearch) writeTreeNode(out *strings.Builder, node *treeNode, prefix, childPrefix string) {
+	out.WriteString(prefix)
+	if len(node.children) > 0 {
+		out.WriteString(node.name)
+	} else {
+		out.WriteString(paint(ss.colors.pathColor(node.name), node.name))
+	}
+	out.WriteString(" (")
+	out.WriteString(paint(ss.colors.line, strconv.FormatUint(node.matches, 10)))
+	out.WriteString(")\n")
+
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i == len(names)-1 {
+			ss.writeTreeNode(out, node.children[name], childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			ss.writeTreeNode(out, node.children[name], childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Built-in file types for --type and --type-not. Each glob is matched against
+// file names, so exact names such as Makefile work too.
+var defaultTypes = map[string][]string{
+	"c":        {"*.c", "*.h"},
+	"cpp":      {"*.cc", "*.cpp", "*.cxx", "*.hh", "*.hpp", "*.hxx", "*.h"},
+	"csharp":   {"*.cs", "*.csx"},
+	"css":      {"*.css", "*.scss", "*.sass", "*.less"},
+	"docker":   {"Dockerfile", "*.dockerfile", "Dockerfile.*", "docker-compose*.yml", "docker-compose*.yaml"},
+	"go":       {"*.go"},
+	"gotest":   {"*_test.go"},
+	"html":     {"*.html", "*.htm", "*.xhtml"},
+	"java":     {"*.java", "*.jsp"},
+	"js":       {"*.js", "*.jsx", "*.mjs", "*.cjs", "*.vue"},
+	"json":     {"*.json", "*.jsonc", "*.geojson"},
+	"kotlin":   {"*.kt", "*.kts"},
+	"lua":      {"*.lua"},
+	"make":     {"Makefile", "makefile", "GNUmakefile", "*.mk", "*.mak"},
+	"markdown": {"*.md", "*.markdown", "*.mdx"},
+	"php":      {"*.php", "*.phtml"},
+	"proto":    {"*.proto"},
+	"py":       {"*.py", "*.pyi", "*.pyw"},
+	"ruby":     {"*.rb", "*.rake", "*.gemspec", "Gemfile", "Rakefile"},
+	"rust":     {"*.rs"},
+	"sh":       {"*.sh", "*.bash", "*.zsh", "*.ksh", ".bashrc", ".bash_profile", ".profile", ".zshrc"},
+	"sql":      {"*.sql"},
+	"swift":    {"*.swift"},
+	"toml":     {"*.toml", "Cargo.lock"},
+	"ts":       {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"txt":      {"*.txt"},
+	"xml":      {"*.xml", "*.xsd", "*.xsl", "*.xslt", "*.svg"},
+	"yaml":     {"*.yml", "*.yaml"},
+}
+
+// typeFilter selects the files searched in directories with --type and
+// --type-not
+type typeFilter struct {
+	types   map[string][]string
+	include []string
+	exclude []string
+}
+
+// Builds the registry of types from the defaults and any --type-add
+// definitions, then resolves the --type and --type-not selections
+func newTypeFilter(opts *Options) (*typeFilter, error) {
+	f := &typeFilter{types: make(map[string][]string, len(defaultTypes))}
+	for name, globs := range defaultTypes {
+		f.types[name] = globs
+	}
+
+	// Definitions are added to any existing type with the same name
+	for _, def := range opts.TypeAdd {
+		kv := strings.SplitN(def, ":", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid type definition %q: expected NAME:GLOB[,GLOB...]", def)
+		}
+		for _, glob := range strings.Split(kv[1], ",") {
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid type definition %q: bad glob %q", def, glob)
+			}
+			f.types[kv[0]] = append(f.types[kv[0]], glob)
+		}
+	}
+
+	for _, name := range opts.Types {
+		globs, ok := f.types[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown file type %q (see --type-list)", name)
+		}
+		f.include = append(f.include, globs...)
+	}
+	for _, name := range opts.TypesNot {
+		globs, ok := f.types[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown file type %q (see --type-list)", name)
+		}
+		f.exclude = append(f.exclude, globs...)
+	}
+	return f, nil
+}
+
+// Reports whether the file called name should be searched. Exclusions win
+// over inclusions, so "-t go -T gotest" searches Go files other than tests.
+func (f *typeFilter) match(name string) bool {
+	if matchAnyGlob(f.exclude, name) {
+		return false
+	}
+	return len(f.include) == 0 || matchAnyGlob(f.include, name)
+}
+
+func matchAnyGlob(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTypes prints each file type and its globs, for --type-list
+func (ss *SuperSearch) ListTypes() {
+	names := make([]string, 0, len(ss.types.types))
+	for name := range ss.types.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&out, "%v: %v\n", paint(ss.colors.path, name), strings.Join(ss.types.types[name], ", "))
+	}
+	ss.print(out.String())
+}
@@ -0,0 +1,179 @@
+package syntax
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Language describes how to highlight a programming or data language
+type Language struct {
+	Name string
+
+	// How files in the language are recognized
+	Extensions   []string
+	Filenames    []string
+	Interpreters []string
+
+	Keywords        []string
+	LineComments    []string
+	BlockComment    [2]string
+	Quotes          string
+	MultilineQuotes []string
+
+	// Whether line comments must be at the start of a word
+	CommentAfterSpace bool
+	// Whether backquoted strings ignore backslash escapes
+	RawBackquote bool
+
+	keywords map[string]bool
+}
+
+// Languages are the built-in languages
+var Languages = []*Language{
+	{
+		Name:       "Go",
+		Extensions: []string{".go"},
+		Keywords: []string{
+			"break", "case", "chan", "const", "continue", "default", "defer",
+			"else", "fallthrough", "for", "func", "go", "goto", "if", "import",
+			"interface", "map", "package", "range", "return", "select",
+			"struct", "switch", "type", "var", "true", "false", "nil", "iota",
+		},
+		LineComments:    []string{"//"},
+		BlockComment:    [2]string{"/*", "*/"},
+		Quotes:          `"'`,
+		MultilineQuotes: []string{"`"},
+		RawBackquote:    true,
+	},
+	{
+		Name:         "Python",
+		Extensions:   []string{".py", ".pyw", ".pyi"},
+		Interpreters: []string{"python", "python2", "python3"},
+		Keywords: []string{
+			"and", "as", "assert", "async", "await", "break", "class",
+			"continue", "def", "del", "elif", "else", "except", "finally",
+			"for", "from", "global", "if", "import", "in", "is", "lambda",
+			"nonlocal", "not", "or", "pass", "raise", "return", "try", "while",
+			"with", "yield", "True", "False", "None",
+		},
+		LineComments:    []string{"#"},
+		Quotes:          `"'`,
+		MultilineQuotes: []string{`"""`, `'''`},
+	},
+	{
+		Name:         "JavaScript",
+		Extensions:   []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts"},
+		Interpreters: []string{"node", "deno", "bun"},
+		Keywords: []string{
+			"abstract", "as", "async", "await", "break", "case", "catch",
+			"class", "const", "continue", "debugger", "declare", "default",
+			"delete", "do", "else", "enum", "export", "extends", "finally",
+			"for", "from", "function", "if", "implements", "import", "in",
+			"instanceof", "interface", "let", "namespace", "new", "of",
+			"private", "protected", "public", "readonly", "return", "static",
+			"super", "switch", "this", "throw", "try", "type", "typeof", "var",
+			"void", "while", "with", "yield", "true", "false", "null",
+			"undefined",
+		},
+		LineComments:    []string{"//"},
+		BlockComment:    [2]string{"/*", "*/"},
+		Quotes:          `"'`,
+		MultilineQuotes: []string{"`"},
+	},
+	{
+		Name:         "Shell",
+		Extensions:   []string{".sh", ".bash", ".zsh", ".ksh"},
+		Filenames:    []string{".bashrc", ".bash_profile", ".profile", ".zshrc"},
+		Interpreters: []string{"sh", "bash", "zsh", "ksh", "dash"},
+		Keywords: []string{
+			"case", "do", "done", "elif", "else", "esac", "export", "fi", "for",
+			"function", "if", "in", "local", "return", "select", "then",
+			"until", "while", "readonly", "declare", "source",
+		},
+		LineComments:      []string{"#"},
+		CommentAfterSpace: true,
+		Quotes:            `"'`,
+	},
+	{
+		Name:              "YAML",
+		Extensions:        []string{".yml", ".yaml"},
+		Keywords:          []string{"true", "false", "null", "yes", "no", "on", "off", "True", "False", "Null"},
+		LineComments:      []string{"#"},
+		CommentAfterSpace: true,
+		Quotes:            `"'`,
+	},
+	{
+		Name:       "JSON",
+		Extensions: []string{".json", ".jsonc", ".geojson"},
+		Keywords:   []string{"true", "false", "null"},
+		Quotes:     `"`,
+	},
+}
+
+func init() {
+	for _, l := range Languages {
+		l.keywords = make(map[string]bool, len(l.Keywords))
+		for _, k := range l.Keywords {
+			l.keywords[k] = true
+		}
+	}
+}
+
+// Detect returns the language of the file at path, based on its extension or
+// name, or failing that the interpreter named in a shebang on its first line.
+// It returns nil if the language isn't known.
+func Detect(path string, firstLine []byte) *Language {
+	name := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(name))
+
+	for _, l := range Languages {
+		for _, e := range l.Extensions {
+			if ext == e {
+				return l
+			}
+		}
+		for _, n := range l.Filenames {
+			if name == n {
+				return l
+			}
+		}
+	}
+
+	if interpreter := shebangInterpreter(firstLine); interpreter != "" {
+		for _, l := range Languages {
+			for _, i := range l.Interpreters {
+				if interpreter == i {
+					return l
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the program run by a shebang line such as "#!/bin/sh" or
+// "#!/usr/bin/env python3"
+func shebangInterpreter(line []byte) string {
+	if !bytes.HasPrefix(line, []byte("#!")) {
+		return ""
+	}
+
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	program := filepath.Base(fields[0])
+	if program == "env" {
+		// Skip options such as env -S
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				return f
+			}
+		}
+		return ""
+	}
+	return program
+}
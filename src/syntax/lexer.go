@@ -0,0 +1,170 @@
+package syntax
+
+import (
+	"bytes"
+)
+
+// Kind is the type of a token
+type Kind int
+
+const (
+	// Plain is text which isn't highlighted
+	Plain Kind = iota
+	// Keyword is a reserved word or literal such as true or null
+	Keyword
+	// String is a string literal, including its quotes
+	String
+	// Comment is a line or block comment
+	Comment
+	// Number is a numeric literal
+	Number
+)
+
+// Token is a highlighted range of a line
+type Token struct {
+	Start int
+	End   int
+	Kind  Kind
+}
+
+// State carries constructs which span multiple lines, such as block comments
+// and multi-line strings, from one line to the next. The zero value is the
+// state at the start of a file.
+type State struct {
+	// The delimiter which ends the current construct
+	end  string
+	kind Kind
+	raw  bool
+}
+
+// Tokenize returns the highlighted tokens in line, in order, given the state
+// at the end of the previous line. It returns the state at the end of line.
+func (l *Language) Tokenize(line []byte, state State) ([]Token, State) {
+	var tokens []Token
+	i := 0
+
+	// Continue a construct from the previous line
+	if state.end != "" {
+		end := findEnd(line, 0, state.end, state.raw)
+		if end < 0 {
+			return []Token{{0, len(line), state.kind}}, state
+		}
+		tokens = append(tokens, Token{0, end, state.kind})
+		i = end
+		state = State{}
+	}
+
+	for i < len(line) {
+		c := line[i]
+
+		if l.isLineComment(line, i) {
+			return append(tokens, Token{i, len(line), Comment}), state
+		}
+
+		if l.BlockComment[0] != "" && bytes.HasPrefix(line[i:], []byte(l.BlockComment[0])) {
+			end := findEnd(line, i+len(l.BlockComment[0]), l.BlockComment[1], true)
+			if end < 0 {
+				return append(tokens, Token{i, len(line), Comment}), State{end: l.BlockComment[1], kind: Comment, raw: true}
+			}
+			tokens = append(tokens, Token{i, end, Comment})
+			i = end
+			continue
+		}
+
+		if q := l.multilineQuote(line, i); q != "" {
+			raw := q == "`" && l.RawBackquote
+			end := findEnd(line, i+len(q), q, raw)
+			if end < 0 {
+				return append(tokens, Token{i, len(line), String}), State{end: q, kind: String, raw: raw}
+			}
+			tokens = append(tokens, Token{i, end, String})
+			i = end
+			continue
+		}
+
+		if bytes.IndexByte([]byte(l.Quotes), c) >= 0 {
+			// Unterminated strings end with the line
+			end := findEnd(line, i+1, string(c), false)
+			if end < 0 {
+				end = len(line)
+			}
+			tokens = append(tokens, Token{i, end, String})
+			i = end
+			continue
+		}
+
+		if isDigit(c) && (i == 0 || !isWord(line[i-1])) {
+			end := i + 1
+			for end < len(line) && (isWord(line[end]) || line[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, Token{i, end, Number})
+			i = end
+			continue
+		}
+
+		if isWord(c) {
+			end := i + 1
+			for end < len(line) && isWord(line[end]) {
+				end++
+			}
+			if l.keywords[string(line[i:end])] {
+				tokens = append(tokens, Token{i, end, Keyword})
+			}
+			i = end
+			continue
+		}
+
+		i++
+	}
+
+	return tokens, state
+}
+
+func (l *Language) isLineComment(line []byte, i int) bool {
+	for _, prefix := range l.LineComments {
+		if !bytes.HasPrefix(line[i:], []byte(prefix)) {
+			continue
+		}
+		// In shell and YAML, # only starts a comment at the start of a word
+		if l.CommentAfterSpace && i > 0 && line[i-1] != ' ' && line[i-1] != '\t' {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (l *Language) multilineQuote(line []byte, i int) string {
+	for _, q := range l.MultilineQuotes {
+		if bytes.HasPrefix(line[i:], []byte(q)) {
+			return q
+		}
+	}
+	return ""
+}
+
+// Returns the offset just past the first delim in line at or after i, or -1
+// if there isn't one. Unless raw, delimiters escaped by a backslash are
+// skipped.
+func findEnd(line []byte, i int, delim string, raw bool) int {
+	for i < len(line) {
+		if !raw && line[i] == '\\' {
+			i += 2
+			continue
+		}
+		if bytes.HasPrefix(line[i:], []byte(delim)) {
+			return i + len(delim)
+		}
+		i++
+	}
+	return -1
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
@@ -0,0 +1,41 @@
+package syntax
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, "Go", Detect("src/main.go", nil).Name)
+	assert.Equal(t, "JavaScript", Detect("app/index.TSX", nil).Name)
+	assert.Equal(t, "Shell", Detect("/home/me/.bashrc", nil).Name)
+	assert.Equal(t, "Python", Detect("bin/tool", []byte("#!/usr/bin/env python3")).Name)
+	assert.Equal(t, "Shell", Detect("configure", []byte("#!/bin/sh -e")).Name)
+	assert.Nil(t, Detect("README", []byte("# Readme")))
+}
+
+func TestTokenize(t *testing.T) {
+	golang := Detect("x.go", nil)
+	tokens, state := golang.Tokenize([]byte(`	return "a\"b", 42 // done`), State{})
+	assert.Equal(t, []Token{
+		{1, 7, Keyword},
+		{8, 14, String},
+		{16, 18, Number},
+		{19, 26, Comment},
+	}, tokens)
+	assert.Equal(t, State{}, state)
+
+	// Block comments and raw strings carry over to the next line
+	tokens, state = golang.Tokenize([]byte("x := `raw \\"), State{})
+	assert.Equal(t, []Token{{5, 11, String}}, tokens)
+	tokens, state = golang.Tokenize([]byte("end` /* open"), state)
+	assert.Equal(t, []Token{{0, 4, String}, {5, 12, Comment}}, tokens)
+	tokens, state = golang.Tokenize([]byte("still */ if"), state)
+	assert.Equal(t, []Token{{0, 8, Comment}, {9, 11, Keyword}}, tokens)
+	assert.Equal(t, State{}, state)
+
+	shell := Detect("x.sh", nil)
+	tokens, _ = shell.Tokenize([]byte("echo $#  # count"), State{})
+	assert.Equal(t, []Token{{9, 16, Comment}}, tokens)
+}